@@ -5,8 +5,11 @@ go 1.24.0
 toolchain go1.24.6
 
 require (
+	github.com/andybalholm/brotli v1.2.0
 	github.com/go-chi/chi/v5 v5.2.3
+	github.com/graphql-go/graphql v0.8.1
 	github.com/oschwald/geoip2-golang v1.13.0
+	golang.org/x/text v0.27.0
 	modernc.org/sqlite v1.39.0
 )
 
@@ -0,0 +1,88 @@
+package airports
+
+import (
+	"math"
+	"sort"
+)
+
+// Cluster summarises the airports in one grid cell of a bounding box query
+type Cluster struct {
+	Centroid Coordinates `json:"centroid"` // Mean position of the airports in the cluster
+	Count    int         `json:"count"`
+	Airport  *Airport    `json:"airport"` // Representative airport (larger and IATA airports preferred)
+}
+
+// Coordinates is a latitude/longitude pair
+type Coordinates struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// ClusterInBoundingBox groups the airports within geographic bounds into
+// square grid cells of cellDegrees. The grid is anchored at -90/-180 rather
+// than at the box, so clusters stay put as the box pans. As with
+// GetInBoundingBox, minLon > maxLon crosses the antimeridian. Clusters are
+// ordered largest first.
+func (s *Service) ClusterInBoundingBox(minLat, maxLat, minLon, maxLon, cellDegrees float64) []Cluster {
+	s.indexes.mu.RLock()
+	defer s.indexes.mu.RUnlock()
+
+	if cellDegrees <= 0 {
+		return []Cluster{}
+	}
+
+	type cell struct {
+		sumLat, sumLon float64
+		count          int
+		best           *Airport
+	}
+	cells := make(map[[2]int]*cell)
+
+	s.indexes.spatial.searchBoxWrapped(minLat, maxLat, minLon, maxLon, func(apt *Airport) {
+		key := [2]int{
+			int(math.Floor((apt.Lat + 90) / cellDegrees)),
+			int(math.Floor((apt.Lon + 180) / cellDegrees)),
+		}
+		c, ok := cells[key]
+		if !ok {
+			c = &cell{}
+			cells[key] = c
+		}
+		c.sumLat += apt.Lat
+		c.sumLon += apt.Lon
+		c.count++
+		if c.best == nil || representativeBefore(apt, c.best) {
+			c.best = apt
+		}
+	})
+
+	clusters := make([]Cluster, 0, len(cells))
+	for _, c := range cells {
+		clusters = append(clusters, Cluster{
+			Centroid: Coordinates{
+				Lat: c.sumLat / float64(c.count),
+				Lon: c.sumLon / float64(c.count),
+			},
+			Count:   c.count,
+			Airport: c.best,
+		})
+	}
+
+	sort.Slice(clusters, func(i, j int) bool {
+		if clusters[i].Count != clusters[j].Count {
+			return clusters[i].Count > clusters[j].Count
+		}
+		return clusters[i].Airport.ICAO < clusters[j].Airport.ICAO
+	})
+
+	return clusters
+}
+
+// representativeBefore reports whether a is a better cluster representative
+// than b: larger airports and those with an IATA code first, then by ICAO
+func representativeBefore(a, b *Airport) bool {
+	if ia, ib := a.Importance(), b.Importance(); ia != ib {
+		return ia > ib
+	}
+	return a.ICAO < b.ICAO
+}
@@ -0,0 +1,59 @@
+package airports
+
+import "testing"
+
+func TestClusterInBoundingBox(t *testing.T) {
+	svc := newSyntheticService(5000)
+
+	all := svc.GetInBoundingBox(-60, 60, -120, 120)
+	clusters := svc.ClusterInBoundingBox(-60, 60, -120, 120, 10)
+
+	total := 0
+	for i, c := range clusters {
+		total += c.Count
+		if c.Airport == nil {
+			t.Fatalf("cluster %d has no representative airport", i)
+		}
+		if i > 0 && c.Count > clusters[i-1].Count {
+			t.Errorf("clusters not ordered by count: %d after %d", c.Count, clusters[i-1].Count)
+		}
+		if abs(int(c.Centroid.Lat-c.Airport.Lat)) > 10 || abs(int(c.Centroid.Lon-c.Airport.Lon)) > 10 {
+			t.Errorf("representative %s is outside its cluster cell", c.Airport.ICAO)
+		}
+	}
+
+	if total != len(all) {
+		t.Errorf("clusters hold %d airports, want %d", total, len(all))
+	}
+	if len(clusters) > 12*24 {
+		t.Errorf("got %d clusters, want at most one per 10 degree cell", len(clusters))
+	}
+}
+
+func TestClusterRepresentative(t *testing.T) {
+	data := AirportDatabase{
+		"AAAA": {ICAO: "AAAA", Name: "Field", Lat: 10.1, Lon: 10.1},
+		"ZZZZ": {ICAO: "ZZZZ", IATA: "ZZZ", Name: "International", Lat: 10.3, Lon: 10.5},
+		"BBBB": {ICAO: "BBBB", Name: "Strip", Lat: 10.2, Lon: 10.3},
+	}
+	svc := &Service{data: data, indexes: BuildIndexes(data)}
+
+	clusters := svc.ClusterInBoundingBox(0, 20, 0, 20, 1)
+	if len(clusters) != 1 {
+		t.Fatalf("got %d clusters, want 1", len(clusters))
+	}
+	c := clusters[0]
+	if c.Count != 3 || c.Airport.ICAO != "ZZZZ" {
+		t.Errorf("got count %d representative %s, want 3 ZZZZ", c.Count, c.Airport.ICAO)
+	}
+	if c.Centroid.Lat < 10.19 || c.Centroid.Lat > 10.21 || c.Centroid.Lon < 10.29 || c.Centroid.Lon > 10.31 {
+		t.Errorf("centroid = %+v, want about 10.2, 10.3", c.Centroid)
+	}
+
+	// A larger airport type outranks an IATA code
+	data["AAAA"] = Airport{ICAO: "AAAA", Name: "Field", Type: TypeLargeAirport, Lat: 10.1, Lon: 10.1}
+	svc.indexes = BuildIndexes(data)
+	if c := svc.ClusterInBoundingBox(0, 20, 0, 20, 1)[0]; c.Airport.ICAO != "AAAA" {
+		t.Errorf("representative = %s, want the large airport AAAA", c.Airport.ICAO)
+	}
+}
@@ -0,0 +1,112 @@
+package airports
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+)
+
+// countryAliases maps country names used by public datasets that differ
+// from the CLDR English names to ISO 3166 codes
+var countryAliases = map[string]string{
+	"burma":                             "MM",
+	"cape verde":                        "CV",
+	"czech republic":                    "CZ",
+	"east timor":                        "TL",
+	"eswatini":                          "SZ",
+	"heard island and mcdonald islands": "HM",
+	"hong kong":                         "HK",
+	"ivory coast":                       "CI",
+	"johnston atoll":                    "UM",
+	"macau":                             "MO",
+	"macao":                             "MO",
+	"midway islands":                    "UM",
+	"north macedonia":                   "MK",
+	"palestine":                         "PS",
+	"saint vincent and the grenadines":  "VC",
+	"south georgia and the islands":     "GS",
+	"svalbard":                          "SJ",
+	"turkiye":                           "TR",
+	"united states of america":          "US",
+	"virgin islands":                    "VI",
+	"wake island":                       "UM",
+	"west bank":                         "PS",
+}
+
+var (
+	countryNamesOnce sync.Once
+	countryNames     map[string]string // Normalised English name -> ISO code
+)
+
+// countryCode resolves an ISO 3166 alpha-2 code or an English country name,
+// such as "United Kingdom" or "Congo (Kinshasa)", to an ISO code
+func countryCode(name string) (string, bool) {
+	name = strings.TrimSpace(name)
+	if len(name) == 2 {
+		if region, err := language.ParseRegion(name); err == nil && region.IsCountry() {
+			return region.String(), true
+		}
+	}
+
+	countryNamesOnce.Do(buildCountryNames)
+	code, ok := countryNames[countryKey(name)]
+	return code, ok
+}
+
+// buildCountryNames indexes the CLDR English names of every current ISO
+// country code, plus the aliases
+func buildCountryNames() {
+	countryNames = make(map[string]string)
+	namer := display.English.Regions()
+
+	for a := 'A'; a <= 'Z'; a++ {
+		for b := 'A'; b <= 'Z'; b++ {
+			region, err := language.ParseRegion(string([]rune{a, b}))
+			// Skip unknown and retired codes (e.g. "BU" for Burma)
+			if err != nil || !region.IsCountry() || region.Canonicalize() != region {
+				continue
+			}
+			name := namer.Name(region)
+			if name == "" {
+				continue
+			}
+			code := region.String()
+
+			countryNames[countryKey(name)] = code
+			// "Myanmar (Burma)" is also known by each part, and "Hong Kong
+			// SAR China" by its short name
+			if before, inner, found := strings.Cut(name, " ("); found {
+				countryNames[countryKey(before)] = code
+				countryNames[countryKey(strings.TrimSuffix(inner, ")"))] = code
+			}
+			if before, found := strings.CutSuffix(name, " SAR China"); found {
+				countryNames[countryKey(before)] = code
+			}
+		}
+	}
+
+	for name, code := range countryAliases {
+		countryNames[countryKey(name)] = code
+	}
+}
+
+// countryKey normalises a country name for matching: accents, case and
+// punctuation are ignored, "&" reads as "and" and "St." as "Saint", so
+// "Congo - Kinshasa" matches "Congo (Kinshasa)" and "St. Lucia" matches
+// "Saint Lucia"
+func countryKey(name string) string {
+	name = strings.ReplaceAll(normalizeText(name), "&", " and ")
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.'
+	})
+	for i, word := range words {
+		if word == "st." || word == "st" {
+			word = "saint"
+		}
+		words[i] = strings.ReplaceAll(word, ".", "")
+	}
+	return strings.Join(words, " ")
+}
@@ -2,11 +2,13 @@ package airports
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 )
 
 // Airport represents a single airport's data
@@ -21,6 +23,14 @@ type Airport struct {
 	Lat       float64 `json:"lat"`
 	Lon       float64 `json:"lon"`
 	Tz        string  `json:"tz"`
+
+	// Optional details, omitted when the source has none
+	Type             string      `json:"type,omitempty"`              // One of the Type constants
+	FAA              string      `json:"faa,omitempty"`               // FAA location identifier (LID), US airports
+	LocalCode        string      `json:"local_code,omitempty"`        // National airport code
+	ScheduledService *bool       `json:"scheduled_service,omitempty"` // Has scheduled airline service
+	Runways          []Runway    `json:"runways,omitempty"`
+	Frequencies      []Frequency `json:"frequencies,omitempty"`
 }
 
 // AirportWithDistance includes distance from search point
@@ -40,13 +50,33 @@ type AirportIndexes struct {
 	ByCity    map[string][]*Airport
 	ByCountry map[string][]*Airport
 	ByState   map[string][]*Airport
+	ByType    map[string][]*Airport // Airports with a type, by type
+	ByLocal   map[string][]*Airport // By FAA identifier and local code
+	sorted    []*Airport            // every airport ordered by ICAO
+	ordered   map[string][]*Airport // every airport ordered by each other sort field
+	spatial   *spatialIndex
+	search    *searchIndex
 	mu        sync.RWMutex
 }
 
-// Service manages airport data and lookups
+// Service manages airport data and lookups. The dataset, its indexes and
+// its load details are guarded by indexes.mu and replaced together.
 type Service struct {
-	data    AirportDatabase
-	indexes *AirportIndexes
+	data       AirportDatabase // The dataset with overrides applied
+	indexes    *AirportIndexes
+	generation uint64 // Incremented whenever the dataset is replaced
+
+	base      AirportDatabase     // The dataset as loaded, before overrides
+	overrides map[string]Override // Administrator corrections by ICAO code
+	versions  []*datasetSnapshot  // Recent dataset versions, oldest first
+
+	path        string        // External dataset file or directory, if any
+	embedded    []byte        // Fallback dataset compiled into the binary
+	source      string        // Where the current dataset was loaded from
+	loadedAt    time.Time     // When the current dataset was loaded
+	lastReload  *ReloadResult // Outcome of the most recent load attempt
+	fingerprint string        // Dataset files as of the most recent load attempt
+	reloadMu    sync.Mutex    // Serialises reloads
 }
 
 // NewService loads and indexes all airport data from embedded JSON
@@ -58,18 +88,25 @@ func NewService(jsonData []byte) (*Service, error) {
 
 	indexes := BuildIndexes(data)
 
-	return &Service{
-		data:    data,
-		indexes: indexes,
-	}, nil
+	s := &Service{
+		data:       data,
+		indexes:    indexes,
+		generation: 1,
+		base:       data,
+		embedded:   jsonData,
+		source:     SourceEmbedded,
+		loadedAt:   time.Now(),
+	}
+	s.recordVersion(newSnapshot(data), s.loadedAt)
+	return s, nil
 }
 
-// LoadAirports reads and parses the embedded airports.json
+// LoadAirports parses airport JSON: an object of airports keyed by ICAO code
 func LoadAirports(jsonData []byte) (AirportDatabase, error) {
 	var airports AirportDatabase
 	err := json.Unmarshal(jsonData, &airports)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse airports.json: %w", err)
+		return nil, fmt.Errorf("failed to parse airport data: %w", err)
 	}
 	return airports, nil
 }
@@ -82,6 +119,11 @@ func BuildIndexes(airports AirportDatabase) *AirportIndexes {
 		ByCity:    make(map[string][]*Airport),
 		ByCountry: make(map[string][]*Airport),
 		ByState:   make(map[string][]*Airport),
+		ByType:    make(map[string][]*Airport),
+		ByLocal:   make(map[string][]*Airport),
+		spatial:   newSpatialIndex(),
+		search:    newSearchIndex(),
+		ordered:   make(map[string][]*Airport),
 	}
 
 	for icao, airport := range airports {
@@ -97,9 +139,9 @@ func BuildIndexes(airports AirportDatabase) *AirportIndexes {
 			indexes.ByIATA[iata] = append(indexes.ByIATA[iata], &apt)
 		}
 
-		// Index by city (case-insensitive)
+		// Index by city (case- and accent-insensitive)
 		if apt.City != "" {
-			city := strings.ToLower(apt.City)
+			city := normalizeText(apt.City)
 			indexes.ByCity[city] = append(indexes.ByCity[city], &apt)
 		}
 
@@ -109,17 +151,89 @@ func BuildIndexes(airports AirportDatabase) *AirportIndexes {
 			indexes.ByCountry[country] = append(indexes.ByCountry[country], &apt)
 		}
 
-		// Index by state (case-insensitive)
+		// Index by state (case- and accent-insensitive)
 		if apt.State != "" {
-			state := strings.ToLower(apt.State)
+			state := normalizeText(apt.State)
 			indexes.ByState[state] = append(indexes.ByState[state], &apt)
 		}
+
+		// Index by type
+		if apt.Type != "" {
+			indexes.ByType[apt.Type] = append(indexes.ByType[apt.Type], &apt)
+		}
+
+		// Index by FAA identifier and local code (usually the same)
+		for _, code := range localCodes(&apt) {
+			indexes.ByLocal[code] = append(indexes.ByLocal[code], &apt)
+		}
+
+		// Index by location
+		indexes.spatial.insert(&apt)
+
+		// Index name, city and code tokens for search
+		indexes.search.insert(&apt)
+
+		indexes.sorted = append(indexes.sorted, &apt)
 	}
 
+	// Keep lists in ICAO order so pages are stable
+	sortByICAO(indexes.sorted)
+	for _, list := range indexes.ByCountry {
+		sortByICAO(list)
+	}
+	for _, list := range indexes.ByState {
+		sortByICAO(list)
+	}
+	for _, list := range indexes.ByType {
+		sortByICAO(list)
+	}
+	for _, list := range indexes.ByLocal {
+		sortByICAO(list)
+	}
+	for field, key := range sortKeys {
+		if field != SortICAO {
+			indexes.ordered[field] = sortedByKey(indexes.sorted, key)
+		}
+	}
+	indexes.spatial.sortCells()
+	indexes.search.finalize()
+
 	return indexes
 }
 
-// GetByCode looks up an airport by ICAO or IATA code
+// localCodes returns an airport's distinct FAA and local codes, upper case
+func localCodes(apt *Airport) []string {
+	var codes []string
+	for _, code := range []string{apt.FAA, apt.LocalCode} {
+		code = strings.ToUpper(code)
+		if code != "" && (len(codes) == 0 || codes[0] != code) {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+// replace takes over the lookup tables of freshly built indexes. The caller
+// holds idx.mu for writing.
+func (idx *AirportIndexes) replace(from *AirportIndexes) {
+	idx.ByICAO = from.ByICAO
+	idx.ByIATA = from.ByIATA
+	idx.ByCity = from.ByCity
+	idx.ByCountry = from.ByCountry
+	idx.ByState = from.ByState
+	idx.ByType = from.ByType
+	idx.ByLocal = from.ByLocal
+	idx.sorted = from.sorted
+	idx.ordered = from.ordered
+	idx.spatial = from.spatial
+	idx.search = from.search
+}
+
+// ErrAirportNotFound is returned for a code no airport has
+var ErrAirportNotFound = errors.New("airport not found")
+
+// GetByCode looks up an airport by ICAO or IATA code, then by FAA
+// identifier or local code
 func (s *Service) GetByCode(code string) (*Airport, error) {
 	s.indexes.mu.RLock()
 	defer s.indexes.mu.RUnlock()
@@ -136,68 +250,12 @@ func (s *Service) GetByCode(code string) (*Airport, error) {
 		return apts[0], nil
 	}
 
-	return nil, fmt.Errorf("airport not found: %s", code)
-}
-
-// Search finds airports matching the query across multiple fields
-func (s *Service) Search(query string, limit, offset int) []*Airport {
-	s.indexes.mu.RLock()
-	defer s.indexes.mu.RUnlock()
-
-	query = strings.ToLower(strings.TrimSpace(query))
-	if query == "" {
-		return nil
-	}
-
-	results := make(map[string]*Airport)
-
-	// Search in codes (exact match priority)
-	queryUpper := strings.ToUpper(query)
-	if apt, ok := s.indexes.ByICAO[queryUpper]; ok {
-		results[apt.ICAO] = apt
-	}
-	if apts, ok := s.indexes.ByIATA[queryUpper]; ok {
-		for _, apt := range apts {
-			results[apt.ICAO] = apt
-		}
-	}
-
-	// Search in names and cities (partial match)
-	for _, apt := range s.data {
-		if _, found := results[apt.ICAO]; found {
-			continue
-		}
-
-		nameLower := strings.ToLower(apt.Name)
-		cityLower := strings.ToLower(apt.City)
-
-		if strings.Contains(nameLower, query) || strings.Contains(cityLower, query) {
-			aptCopy := apt
-			results[apt.ICAO] = &aptCopy
-		}
-	}
-
-	// Convert to slice
-	airports := make([]*Airport, 0, len(results))
-	for _, apt := range results {
-		airports = append(airports, apt)
-	}
-
-	// Sort by name
-	sort.Slice(airports, func(i, j int) bool {
-		return airports[i].Name < airports[j].Name
-	})
-
-	// Apply pagination
-	if offset >= len(airports) {
-		return []*Airport{}
-	}
-	end := offset + limit
-	if end > len(airports) {
-		end = len(airports)
+	// Try FAA and local codes
+	if apts, ok := s.indexes.ByLocal[code]; ok && len(apts) > 0 {
+		return apts[0], nil
 	}
 
-	return airports[offset:end]
+	return nil, fmt.Errorf("%w: %s", ErrAirportNotFound, code)
 }
 
 // GetByCity returns all airports in a city
@@ -205,8 +263,7 @@ func (s *Service) GetByCity(city string) []*Airport {
 	s.indexes.mu.RLock()
 	defer s.indexes.mu.RUnlock()
 
-	cityLower := strings.ToLower(city)
-	return s.indexes.ByCity[cityLower]
+	return s.indexes.ByCity[normalizeText(strings.TrimSpace(city))]
 }
 
 // GetByCountry returns all airports in a country
@@ -223,39 +280,32 @@ func (s *Service) GetByState(state string) []*Airport {
 	s.indexes.mu.RLock()
 	defer s.indexes.mu.RUnlock()
 
-	stateLower := strings.ToLower(state)
-	return s.indexes.ByState[stateLower]
+	return s.indexes.ByState[normalizeText(strings.TrimSpace(state))]
 }
 
-// GetNearby finds airports within radius (km) of coordinates
-func (s *Service) GetNearby(lat, lon, radiusKm float64, limit int) []*Airport {
+// GetByType returns all airports of a type
+func (s *Service) GetByType(airportType string) []*Airport {
 	s.indexes.mu.RLock()
 	defer s.indexes.mu.RUnlock()
 
-	type result struct {
-		airport  *Airport
-		distance float64
-	}
-
-	results := []result{}
+	return s.indexes.ByType[airportType]
+}
 
-	for _, apt := range s.data {
-		dist := haversine(lat, lon, apt.Lat, apt.Lon)
-		if dist <= radiusKm {
-			aptCopy := apt
-			results = append(results, result{&aptCopy, dist})
-		}
-	}
+// GetNearby finds airports within radius (km) of coordinates
+func (s *Service) GetNearby(lat, lon, radiusKm float64, limit int) []*Airport {
+	s.indexes.mu.RLock()
+	defer s.indexes.mu.RUnlock()
 
-	// Sort by distance
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].distance < results[j].distance
-	})
+	results := s.indexes.spatial.withinRadius(lat, lon, radiusKm, nil)
+	sortByDistance(results)
 
 	// Apply limit
 	if limit > len(results) {
 		limit = len(results)
 	}
+	if limit < 0 {
+		limit = 0
+	}
 
 	airports := make([]*Airport, limit)
 	for i := 0; i < limit; i++ {
@@ -270,88 +320,66 @@ func (s *Service) GetNearbyWithDistance(lat, lon, radiusKm float64, limit int, u
 	s.indexes.mu.RLock()
 	defer s.indexes.mu.RUnlock()
 
-	type result struct {
-		airport  Airport
-		distance float64
-	}
-
-	results := []result{}
-
-	for _, apt := range s.data {
-		dist := haversine(lat, lon, apt.Lat, apt.Lon)
-		if dist <= radiusKm {
-			results = append(results, result{apt, dist})
-		}
-	}
-
-	// Sort by distance
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].distance < results[j].distance
-	})
+	results := s.indexes.spatial.withinRadius(lat, lon, radiusKm, nil)
+	sortByDistance(results)
 
 	// Apply limit
 	if limit > len(results) {
 		limit = len(results)
 	}
-
-	// Convert distances based on unit system
-	airports := make([]AirportWithDistance, limit)
-	for i := 0; i < limit; i++ {
-		distance, unit := ConvertDistance(results[i].distance, units)
-		airports[i] = AirportWithDistance{
-			Airport:      results[i].airport,
-			Distance:     distance,
-			DistanceUnit: unit,
-		}
+	if limit < 0 {
+		limit = 0
 	}
 
-	return airports
+	return withDistance(results[:limit], units)
 }
 
-// GetInBoundingBox finds airports within geographic bounds
-func (s *Service) GetInBoundingBox(minLat, maxLat, minLon, maxLon float64) []*Airport {
+// Nearest returns the k airports closest to coordinates that match the
+// filters, at any distance, sorted by great-circle distance
+func (s *Service) Nearest(lat, lon float64, k int, filters Filters, units string) []AirportWithDistance {
 	s.indexes.mu.RLock()
 	defer s.indexes.mu.RUnlock()
 
-	results := []*Airport{}
-
-	for _, apt := range s.data {
-		if apt.Lat >= minLat && apt.Lat <= maxLat &&
-			apt.Lon >= minLon && apt.Lon <= maxLon {
-			aptCopy := apt
-			results = append(results, &aptCopy)
-		}
+	var match func(*Airport) bool
+	if !filters.IsEmpty() {
+		match = filters.Matches
 	}
 
-	return results
+	return withDistance(s.indexes.spatial.nearest(lat, lon, k, match), units)
 }
 
-// GetAll returns all airports (paginated)
-func (s *Service) GetAll(limit, offset int) []*Airport {
+// GetInBoundingBox finds airports within geographic bounds. A box with
+// minLon > maxLon crosses the antimeridian.
+func (s *Service) GetInBoundingBox(minLat, maxLat, minLon, maxLon float64) []*Airport {
 	s.indexes.mu.RLock()
 	defer s.indexes.mu.RUnlock()
 
-	airports := make([]*Airport, 0, len(s.data))
-	for _, apt := range s.data {
-		aptCopy := apt
-		airports = append(airports, &aptCopy)
-	}
-
-	// Sort by ICAO code
-	sort.Slice(airports, func(i, j int) bool {
-		return airports[i].ICAO < airports[j].ICAO
+	results := []*Airport{}
+	s.indexes.spatial.searchBoxWrapped(minLat, maxLat, minLon, maxLon, func(apt *Airport) {
+		results = append(results, apt)
 	})
 
-	// Apply pagination
-	if offset >= len(airports) {
-		return []*Airport{}
-	}
-	end := offset + limit
-	if end > len(airports) {
-		end = len(airports)
+	return results
+}
+
+// withDistance converts distances based on unit system
+func withDistance(results []airportDistance, units string) []AirportWithDistance {
+	airports := make([]AirportWithDistance, len(results))
+	for i, r := range results {
+		distance, unit := ConvertDistance(r.distance, units)
+		airports[i] = AirportWithDistance{
+			Airport:      *r.airport,
+			Distance:     distance,
+			DistanceUnit: unit,
+		}
 	}
+	return airports
+}
 
-	return airports[offset:end]
+// GetAll returns all airports (paginated)
+func (s *Service) GetAll(limit, offset int) []*Airport {
+	airports, _, _ := s.List(ListOptions{Page: Page{Limit: limit, Offset: offset}})
+	return airports
 }
 
 // GetCountries returns list of all countries with airport counts
@@ -391,37 +419,96 @@ func (s *Service) Stats() map[string]interface{} {
 	s.indexes.mu.RLock()
 	defer s.indexes.mu.RUnlock()
 
+	dataset := map[string]interface{}{
+		"source":     s.source,
+		"generation": s.generation,
+		"loaded_at":  s.loadedAt,
+		"overrides":  len(s.overrides),
+	}
+	if current := s.currentVersion(); current.Version != "" {
+		dataset["version"] = current.Version
+		dataset["hash"] = current.Hash
+	}
+	if s.path != "" {
+		dataset["path"] = s.path
+	}
+	if s.lastReload != nil {
+		reload := *s.lastReload
+		dataset["last_reload"] = reload
+	}
+
+	types := make(map[string]int, len(s.indexes.ByType))
+	for t, list := range s.indexes.ByType {
+		types[t] = len(list)
+	}
+
 	return map[string]interface{}{
 		"total_airports": len(s.data),
+		"types":          types,
 		"countries":      len(s.indexes.ByCountry),
 		"cities":         len(s.indexes.ByCity),
 		"with_iata":      len(s.indexes.ByIATA),
+		"dataset":        dataset,
 	}
 }
 
+// Generation identifies the loaded dataset. It changes whenever the dataset
+// is replaced, so callers can tell when derived caches are stale.
+func (s *Service) Generation() uint64 {
+	s.indexes.mu.RLock()
+	defer s.indexes.mu.RUnlock()
+
+	return s.generation
+}
+
 // GetRawData returns the complete airport database as JSON
 func (s *Service) GetRawData() AirportDatabase {
+	s.indexes.mu.RLock()
+	defer s.indexes.mu.RUnlock()
+
 	return s.data
 }
 
 // Unit conversion constants
 const (
-	KmToMiles      = 0.621371
-	MilesToKm      = 1.60934
-	MetersToFeet   = 3.28084
-	FeetToMeters   = 0.3048
+	KmToMiles         = 0.621371
+	MilesToKm         = 1.60934
+	KmToNauticalMiles = 1 / 1.852
+	MetersToFeet      = 3.28084
+	FeetToMeters      = 0.3048
 )
 
 // Unit system types
 const (
 	UnitImperial = "imperial"
 	UnitMetric   = "metric"
+	UnitNautical = "nautical" // Aviation: nautical miles and feet
 )
 
+// unitAliases maps accepted unit parameter values to unit systems
+var unitAliases = map[string]string{
+	"imperial":   UnitImperial,
+	"mi":         UnitImperial,
+	"miles":      UnitImperial,
+	"metric":     UnitMetric,
+	"m":          UnitMetric,
+	"km":         UnitMetric,
+	"kilometers": UnitMetric,
+	"nautical":   UnitNautical,
+	"aviation":   UnitNautical,
+	"nm":         UnitNautical,
+	"nmi":        UnitNautical,
+}
+
+// sortByICAO orders airports by ICAO code
+func sortByICAO(airports []*Airport) {
+	sort.Slice(airports, func(i, j int) bool {
+		return airports[i].ICAO < airports[j].ICAO
+	})
+}
+
 // haversine calculates the distance between two points on Earth (in km)
 func haversine(lat1, lon1, lat2, lon2 float64) float64 {
-	const earthRadius = 6371.0 // km
-
 	// Convert to radians
 	lat1Rad := lat1 * math.Pi / 180
 	lat2Rad := lat2 * math.Pi / 180
@@ -434,33 +521,51 @@ func haversine(lat1, lon1, lat2, lon2 float64) float64 {
 
 	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
 
-	return earthRadius * c
+	return earthRadiusKm * c
 }
 
 // ConvertDistance converts km to the specified unit system
 func ConvertDistance(distanceKm float64, units string) (float64, string) {
-	if units == UnitMetric {
+	switch units {
+	case UnitMetric:
 		return distanceKm, "km"
+	case UnitNautical:
+		return distanceKm * KmToNauticalMiles, "nm"
 	}
 	// Default to imperial
 	return distanceKm * KmToMiles, "mi"
 }
 
+// DistanceToKm converts a distance in the specified unit system to km
+func DistanceToKm(distance float64, units string) float64 {
+	switch units {
+	case UnitMetric:
+		return distance
+	case UnitNautical:
+		return distance / KmToNauticalMiles
+	}
+	return distance / KmToMiles
+}
+
 // ConvertElevation converts feet to the specified unit system
 func ConvertElevation(elevationFeet int, units string) (float64, string) {
 	if units == UnitMetric {
 		return float64(elevationFeet) * FeetToMeters, "m"
 	}
-	// Default to imperial
+	// Imperial and nautical both use feet
 	return float64(elevationFeet), "ft"
 }
 
-// ParseUnits normalizes unit parameter to imperial or metric
+// ParseUnits normalizes unit parameter to imperial, metric or nautical
 func ParseUnits(unitParam string) string {
-	switch strings.ToLower(unitParam) {
-	case "metric", "m", "km", "kilometers":
-		return UnitMetric
-	default:
-		return UnitImperial
+	if units, ok := unitAliases[strings.ToLower(strings.TrimSpace(unitParam))]; ok {
+		return units
 	}
+	return UnitImperial
+}
+
+// ValidUnits reports whether a unit parameter names a unit system
+func ValidUnits(unitParam string) bool {
+	_, ok := unitAliases[strings.ToLower(strings.TrimSpace(unitParam))]
+	return ok
 }
@@ -1,11 +1,33 @@
 package airports
 
 import (
+	"os"
 	"testing"
 )
 
+// testDataPath is a small dataset covering the airports the tests look up
+const testDataPath = "testdata/airports.json"
+
+func loadTestData(tb testing.TB) []byte {
+	tb.Helper()
+	data, err := os.ReadFile(testDataPath)
+	if err != nil {
+		tb.Fatalf("Failed to read %s: %v", testDataPath, err)
+	}
+	return data
+}
+
+func newTestService(tb testing.TB) *Service {
+	tb.Helper()
+	svc, err := NewService(loadTestData(tb))
+	if err != nil {
+		tb.Fatalf("Failed to create service: %v", err)
+	}
+	return svc
+}
+
 func TestLoadAirports(t *testing.T) {
-	data, err := LoadAirports()
+	data, err := LoadAirports(loadTestData(t))
 	if err != nil {
 		t.Fatalf("Failed to load airports: %v", err)
 	}
@@ -18,7 +40,7 @@ func TestLoadAirports(t *testing.T) {
 }
 
 func TestBuildIndexes(t *testing.T) {
-	data, err := LoadAirports()
+	data, err := LoadAirports(loadTestData(t))
 	if err != nil {
 		t.Fatalf("Failed to load airports: %v", err)
 	}
@@ -37,10 +59,7 @@ func TestBuildIndexes(t *testing.T) {
 }
 
 func TestNewService(t *testing.T) {
-	svc, err := NewService()
-	if err != nil {
-		t.Fatalf("Failed to create service: %v", err)
-	}
+	svc := newTestService(t)
 
 	stats := svc.Stats()
 	total, ok := stats["total_airports"].(int)
@@ -52,10 +71,7 @@ func TestNewService(t *testing.T) {
 }
 
 func TestGetByCode(t *testing.T) {
-	svc, err := NewService()
-	if err != nil {
-		t.Fatalf("Failed to create service: %v", err)
-	}
+	svc := newTestService(t)
 
 	tests := []struct {
 		code     string
@@ -83,10 +99,7 @@ func TestGetByCode(t *testing.T) {
 }
 
 func TestSearch(t *testing.T) {
-	svc, err := NewService()
-	if err != nil {
-		t.Fatalf("Failed to create service: %v", err)
-	}
+	svc := newTestService(t)
 
 	tests := []struct {
 		query    string
@@ -94,13 +107,13 @@ func TestSearch(t *testing.T) {
 	}{
 		{"New York", 1},
 		{"JFK", 1},
-		{"International", 100},
-		{"Airport", 1000},
+		{"International", 10},
+		{"Airport", 30},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.query, func(t *testing.T) {
-			results := svc.Search(tt.query, 1000, 0)
+			results, _ := svc.Search(tt.query, 1000, 0)
 			if len(results) < tt.minCount {
 				t.Errorf("Expected at least %d results, got %d", tt.minCount, len(results))
 			}
@@ -109,10 +122,7 @@ func TestSearch(t *testing.T) {
 }
 
 func TestGetNearby(t *testing.T) {
-	svc, err := NewService()
-	if err != nil {
-		t.Fatalf("Failed to create service: %v", err)
-	}
+	svc := newTestService(t)
 
 	// JFK coordinates
 	results := svc.GetNearby(40.6398, -73.7789, 50, 10)
@@ -131,10 +141,7 @@ func TestGetNearby(t *testing.T) {
 }
 
 func TestGetInBoundingBox(t *testing.T) {
-	svc, err := NewService()
-	if err != nil {
-		t.Fatalf("Failed to create service: %v", err)
-	}
+	svc := newTestService(t)
 
 	// Box around New York area
 	results := svc.GetInBoundingBox(40.0, 41.0, -74.0, -73.0)
@@ -159,8 +166,11 @@ func TestHaversine(t *testing.T) {
 }
 
 func BenchmarkLoadAirports(b *testing.B) {
+	jsonData := loadTestData(b)
+
+	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := LoadAirports()
+		_, err := LoadAirports(jsonData)
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -168,10 +178,7 @@ func BenchmarkLoadAirports(b *testing.B) {
 }
 
 func BenchmarkSearch(b *testing.B) {
-	svc, err := NewService()
-	if err != nil {
-		b.Fatal(err)
-	}
+	svc := newTestService(b)
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
@@ -180,10 +187,7 @@ func BenchmarkSearch(b *testing.B) {
 }
 
 func BenchmarkGetByCode(b *testing.B) {
-	svc, err := NewService()
-	if err != nil {
-		b.Fatal(err)
-	}
+	svc := newTestService(b)
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
@@ -192,10 +196,7 @@ func BenchmarkGetByCode(b *testing.B) {
 }
 
 func BenchmarkGetNearby(b *testing.B) {
-	svc, err := NewService()
-	if err != nil {
-		b.Fatal(err)
-	}
+	svc := newTestService(b)
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
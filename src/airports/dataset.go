@@ -0,0 +1,256 @@
+package airports
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SourceEmbedded names the dataset compiled into the binary
+const SourceEmbedded = "embedded"
+
+// Reload triggers
+const (
+	TriggerStartup = "startup"
+	TriggerAdmin   = "admin"
+	TriggerSignal  = "signal"
+	TriggerWatcher = "watcher"
+)
+
+// ReloadResult reports the outcome of a dataset load
+type ReloadResult struct {
+	Trigger    string    `json:"trigger"`
+	Time       time.Time `json:"time"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
+	Source     string    `json:"source"`      // Path read, or "embedded"
+	Files      int       `json:"files"`       // JSON files read from the source
+	Airports   int       `json:"airports"`    // Airports served after the load
+	Generation uint64    `json:"generation"`  // Generation served after the load
+	Version    string    `json:"version"`     // Dataset version served after the load
+	DurationMs float64   `json:"duration_ms"` // Time spent reading and indexing
+}
+
+// ErrEmptyDataset is returned when a dataset source holds no airports
+var ErrEmptyDataset = errors.New("dataset contains no airports")
+
+// NewServiceFromPath loads airport data from path, a JSON file or a
+// directory of JSON files, falling back to the embedded JSON when path does
+// not exist or cannot be loaded. The service can later Reload from the same
+// path.
+func NewServiceFromPath(path string, embedded []byte) (*Service, error) {
+	s := &Service{path: path, embedded: embedded, indexes: &AirportIndexes{}}
+	result, err := s.Reload(TriggerStartup)
+	if err == nil {
+		return s, nil
+	}
+	if result.Source == SourceEmbedded {
+		return nil, fmt.Errorf("failed to load airports: %w", err)
+	}
+
+	// The external dataset is broken; serve the embedded copy until it is fixed
+	log.Printf("Airports: failed to load %s, using embedded data: %v", path, err)
+	data, loadErr := LoadAirports(embedded)
+	if loadErr != nil {
+		return nil, fmt.Errorf("failed to load airports: %w", loadErr)
+	}
+	s.base = data
+	s.swap(data, BuildIndexes(data), SourceEmbedded, time.Now())
+	s.lastReload.Generation = s.generation
+	s.lastReload.Version = s.currentVersion().Version
+	s.lastReload.Airports = len(data)
+	return s, nil
+}
+
+// Path returns the external dataset path the service reloads from
+func (s *Service) Path() string {
+	return s.path
+}
+
+// Reload reads the dataset again and swaps it in atomically: requests in
+// flight finish against the old dataset and later ones see the new one.
+// When the external path does not exist the embedded data is used. On
+// failure the current dataset stays in place. Either way the result is
+// recorded for Stats.
+func (s *Service) Reload(trigger string) (ReloadResult, error) {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	start := time.Now()
+	result := ReloadResult{Trigger: trigger, Time: start}
+
+	// Fingerprint before reading, so a write that lands mid-load is seen
+	// by the next watcher poll
+	fingerprint := datasetFingerprint(s.path)
+	data, source, files, err := s.readDataset()
+	result.Source, result.Files = source, files
+
+	if err == nil {
+		// Administrator overrides apply to every dataset loaded
+		s.base = data
+		data = mergeOverrides(data, s.overrides)
+		indexes := BuildIndexes(data)
+		s.swap(data, indexes, source, start)
+		result.Success = true
+	}
+	result.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+
+	s.indexes.mu.Lock()
+	if err != nil {
+		result.Error = err.Error()
+	}
+	result.Generation = s.generation
+	result.Version = s.currentVersion().Version
+	result.Airports = len(s.data)
+	s.lastReload = &result
+	s.fingerprint = fingerprint
+	s.indexes.mu.Unlock()
+
+	return result, err
+}
+
+// swap replaces the dataset and its indexes and advances the generation
+func (s *Service) swap(data AirportDatabase, indexes *AirportIndexes, source string, loadedAt time.Time) {
+	snap := newSnapshot(data)
+
+	s.indexes.mu.Lock()
+	defer s.indexes.mu.Unlock()
+
+	s.indexes.replace(indexes)
+	s.data = data
+	s.source = source
+	s.loadedAt = loadedAt
+	s.generation++
+	s.recordVersion(snap, loadedAt)
+}
+
+// readDataset loads the external dataset, or the embedded one when the
+// path is unset or missing
+func (s *Service) readDataset() (AirportDatabase, string, int, error) {
+	files, err := datasetFiles(s.path)
+	if err != nil {
+		return nil, s.path, 0, err
+	}
+	if len(files) == 0 {
+		data, err := LoadAirports(s.embedded)
+		if err == nil && len(data) == 0 {
+			err = ErrEmptyDataset
+		}
+		return data, SourceEmbedded, 0, err
+	}
+
+	// Later files override earlier ones, so a directory can hold a base
+	// dataset plus small correction files
+	data := make(AirportDatabase)
+	for _, file := range files {
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			return nil, s.path, len(files), err
+		}
+		part, err := LoadAirports(raw)
+		if err != nil {
+			return nil, s.path, len(files), fmt.Errorf("%s: %w", filepath.Base(file), err)
+		}
+		for icao, apt := range part {
+			data[icao] = apt
+		}
+	}
+	if len(data) == 0 {
+		return nil, s.path, len(files), ErrEmptyDataset
+	}
+	return data, s.path, len(files), nil
+}
+
+// datasetFiles lists the JSON files at path: the file itself, or the .json
+// files in a directory in name order. A missing path has no files.
+func datasetFiles(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || !strings.EqualFold(filepath.Ext(name), ".json") {
+			continue
+		}
+		files = append(files, filepath.Join(path, name))
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// datasetFingerprint summarises the names, sizes and modification times of
+// the dataset files, so a poll can tell when they change
+func datasetFingerprint(path string) string {
+	files, err := datasetFiles(path)
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	var b strings.Builder
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(&b, "%s|%d|%d\n", file, info.Size(), info.ModTime().UnixNano())
+	}
+	return b.String()
+}
+
+// ReloadIfChanged reloads the dataset when its files have changed since
+// the last load. It reports whether a reload was attempted.
+func (s *Service) ReloadIfChanged(trigger string) (bool, ReloadResult, error) {
+	s.indexes.mu.RLock()
+	last := s.fingerprint
+	s.indexes.mu.RUnlock()
+
+	if datasetFingerprint(s.path) == last {
+		return false, ReloadResult{}, nil
+	}
+	result, err := s.Reload(trigger)
+	return true, result, err
+}
+
+// Watch polls the dataset path every interval and reloads it when it
+// changes, until stop is closed
+func (s *Service) Watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			reloaded, result, err := s.ReloadIfChanged(TriggerWatcher)
+			if !reloaded {
+				continue
+			}
+			if err != nil {
+				log.Printf("Airports: reload of %s failed, keeping generation %d: %v", s.path, result.Generation, err)
+			} else {
+				log.Printf("Airports: reloaded %d airports from %s (generation %d)", result.Airports, result.Source, result.Generation)
+			}
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package airports
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const embeddedTestData = `{"EGLL": {"icao": "EGLL", "iata": "LHR", "name": "London Heathrow Airport", "country": "GB", "lat": 51.4706, "lon": -0.4619}}`
+
+func writeDataset(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Make each write visible to the fingerprint even on coarse clocks
+	later := time.Now().Add(time.Duration(len(content)) * time.Second)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewServiceFromPathFallback(t *testing.T) {
+	dir := t.TempDir()
+
+	svc, err := NewServiceFromPath(filepath.Join(dir, "missing.json"), []byte(embeddedTestData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.GetByCode("LHR"); err != nil {
+		t.Errorf("expected the embedded data when the path is missing: %v", err)
+	}
+	dataset := svc.Stats()["dataset"].(map[string]interface{})
+	if dataset["source"] != SourceEmbedded || dataset["generation"] != uint64(1) {
+		t.Errorf("expected embedded generation 1, got %v", dataset)
+	}
+
+	broken := filepath.Join(dir, "broken.json")
+	writeDataset(t, broken, `{"KJFK": {`)
+	svc, err = NewServiceFromPath(broken, []byte(embeddedTestData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataset = svc.Stats()["dataset"].(map[string]interface{})
+	last := dataset["last_reload"].(ReloadResult)
+	if dataset["source"] != SourceEmbedded || last.Success || last.Error == "" || last.Airports != 1 {
+		t.Errorf("expected a recorded failure and embedded data, got %v", dataset)
+	}
+}
+
+func TestServiceReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "airports.json")
+	writeDataset(t, path, `{"KJFK": {"icao": "KJFK", "iata": "JFK", "name": "John F Kennedy International Airport", "country": "US"}}`)
+
+	svc, err := NewServiceFromPath(path, []byte(embeddedTestData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.GetByCode("LHR"); err == nil {
+		t.Error("expected the external dataset to replace the embedded one")
+	}
+	generation := svc.Generation()
+
+	if reloaded, _, _ := svc.ReloadIfChanged(TriggerWatcher); reloaded {
+		t.Error("expected no reload while the file is unchanged")
+	}
+
+	writeDataset(t, path, `{"KLGA": {"icao": "KLGA", "iata": "LGA", "name": "La Guardia Airport", "country": "US"}}`)
+	reloaded, result, err := svc.ReloadIfChanged(TriggerWatcher)
+	if !reloaded || err != nil || !result.Success {
+		t.Fatalf("expected a successful reload after the file changed, got %v %+v %v", reloaded, result, err)
+	}
+	if _, err := svc.GetByCode("LGA"); err != nil {
+		t.Errorf("expected the new airport after reload: %v", err)
+	}
+	if _, err := svc.GetByCode("JFK"); err == nil {
+		t.Error("expected the old airport to be gone after reload")
+	}
+	if svc.Generation() != generation+1 || result.Generation != generation+1 {
+		t.Errorf("generation = %d, want %d", svc.Generation(), generation+1)
+	}
+
+	// A broken file keeps the current dataset
+	writeDataset(t, path, `[]`)
+	result, err = svc.Reload(TriggerAdmin)
+	if err == nil || result.Success {
+		t.Fatal("expected the reload to fail")
+	}
+	if _, err := svc.GetByCode("LGA"); err != nil {
+		t.Errorf("expected the previous dataset to stay after a failed reload: %v", err)
+	}
+	if svc.Generation() != generation+1 {
+		t.Errorf("a failed reload changed the generation to %d", svc.Generation())
+	}
+}
+
+func TestServiceReloadDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeDataset(t, filepath.Join(dir, "10-base.json"), `{
+		"KJFK": {"icao": "KJFK", "name": "Old Name", "country": "US"},
+		"KLGA": {"icao": "KLGA", "name": "La Guardia Airport", "country": "US"}
+	}`)
+	writeDataset(t, filepath.Join(dir, "20-fixes.json"), `{"KJFK": {"icao": "KJFK", "name": "John F Kennedy International Airport", "country": "US"}}`)
+	writeDataset(t, filepath.Join(dir, "notes.txt"), `not json`)
+
+	svc, err := NewServiceFromPath(dir, []byte(embeddedTestData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	apt, err := svc.GetByCode("KJFK")
+	if err != nil || apt.Name != "John F Kennedy International Airport" {
+		t.Errorf("expected later files to override earlier ones, got %v %v", apt, err)
+	}
+	last := svc.Stats()["dataset"].(map[string]interface{})["last_reload"].(ReloadResult)
+	if last.Source != dir || last.Files != 2 || last.Airports != 2 {
+		t.Errorf("expected 2 airports from 2 files in %s, got %+v", dir, last)
+	}
+}
+
+func TestServiceReloadConcurrentReads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "airports.json")
+	writeDataset(t, path, embeddedTestData)
+
+	svc, err := NewServiceFromPath(path, []byte(embeddedTestData))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			if _, err := svc.Reload(TriggerAdmin); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	// Every read sees a complete dataset, old or new
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		if _, err := svc.GetByCode("EGLL"); err != nil {
+			t.Fatalf("lookup failed during reload: %v", err)
+		}
+		if got := svc.GetNearby(51.47, -0.46, 10, 5); len(got) != 1 {
+			t.Fatalf("expected 1 nearby airport during reload, got %d", len(got))
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package airports
+
+import (
+	"slices"
+	"strings"
+)
+
+// Filters restricts which airports a query returns. Zero values match everything.
+type Filters struct {
+	Country      string // ISO country code (case-insensitive)
+	State        string // State/region name (case- and accent-insensitive)
+	City         string // City name (case- and accent-insensitive)
+	HasIATA      *bool  // Require (true) or exclude (false) airports with an IATA code
+	MinElevation *int   // Minimum elevation in feet (inclusive)
+	MaxElevation *int   // Maximum elevation in feet (inclusive)
+	Tz           string // IANA timezone (case-insensitive)
+	NamePrefix   string // Name starts with (case- and accent-insensitive)
+
+	Types            []string // Airport is one of these types
+	ExcludeTypes     []string // Airport is none of these types
+	ScheduledService *bool    // Require (true) or exclude (false) scheduled service; unknown matches neither
+	MinRunwayLength  *int     // An open runway at least this long, in feet
+	RunwaySurface    string   // An open runway with this surface code (case-insensitive)
+}
+
+// IsEmpty reports whether no filters are set
+func (f Filters) IsEmpty() bool {
+	return f.Country == "" && f.State == "" && f.City == "" && f.HasIATA == nil &&
+		f.MinElevation == nil && f.MaxElevation == nil && f.Tz == "" && f.NamePrefix == "" &&
+		len(f.Types) == 0 && len(f.ExcludeTypes) == 0 && f.ScheduledService == nil &&
+		f.MinRunwayLength == nil && f.RunwaySurface == ""
+}
+
+// Matches reports whether an airport satisfies every filter
+func (f Filters) Matches(apt *Airport) bool {
+	if f.Country != "" && !strings.EqualFold(apt.Country, strings.TrimSpace(f.Country)) {
+		return false
+	}
+	if f.State != "" && normalizeText(apt.State) != normalizeText(strings.TrimSpace(f.State)) {
+		return false
+	}
+	if f.City != "" && normalizeText(apt.City) != normalizeText(strings.TrimSpace(f.City)) {
+		return false
+	}
+	if f.HasIATA != nil && (apt.IATA != "") != *f.HasIATA {
+		return false
+	}
+	if f.MinElevation != nil && apt.Elevation < *f.MinElevation {
+		return false
+	}
+	if f.MaxElevation != nil && apt.Elevation > *f.MaxElevation {
+		return false
+	}
+	if f.Tz != "" && !strings.EqualFold(apt.Tz, strings.TrimSpace(f.Tz)) {
+		return false
+	}
+	if f.NamePrefix != "" && !strings.HasPrefix(normalizeText(apt.Name), normalizeText(strings.TrimSpace(f.NamePrefix))) {
+		return false
+	}
+	if len(f.Types) > 0 && !slices.Contains(f.Types, apt.Type) {
+		return false
+	}
+	if slices.Contains(f.ExcludeTypes, apt.Type) {
+		return false
+	}
+	if f.ScheduledService != nil && (apt.ScheduledService == nil || *apt.ScheduledService != *f.ScheduledService) {
+		return false
+	}
+	if f.MinRunwayLength != nil || f.RunwaySurface != "" {
+		minLength := 0
+		if f.MinRunwayLength != nil {
+			minLength = *f.MinRunwayLength
+		}
+		if !apt.hasRunway(minLength, strings.TrimSpace(f.RunwaySurface)) {
+			return false
+		}
+	}
+	return true
+}
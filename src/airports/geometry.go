@@ -0,0 +1,331 @@
+package airports
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// ErrInvalidGeometry is returned for a polygon or route that cannot be queried
+var ErrInvalidGeometry = errors.New("invalid geometry")
+
+// routeSampleMinKm is the smallest spacing of the points sampled along a
+// route to find corridor candidates
+const routeSampleMinKm = 10.0
+
+// Polygon is an outer ring followed by any holes. Rings need not repeat
+// their first position at the end.
+type Polygon [][]Coordinates
+
+// AirportAlongRoute is an airport near a route, with its distance from the
+// route and how far along the route its closest point lies
+type AirportAlongRoute struct {
+	AirportWithDistance
+	AlongTrack float64 `json:"along_track"` // In DistanceUnit, from the start of the route
+}
+
+// WithinPolygon returns the airports matching the filters inside any of the
+// polygons, ordered by ICAO. Rings that cross the antimeridian are unwrapped,
+// so polygons need not be split at 180.
+func (s *Service) WithinPolygon(polygons []Polygon, filters Filters) ([]*Airport, error) {
+	if len(polygons) == 0 {
+		return nil, fmt.Errorf("%w: no polygons", ErrInvalidGeometry)
+	}
+
+	prepared := make([][][]Coordinates, len(polygons))
+	for i, polygon := range polygons {
+		if len(polygon) == 0 {
+			return nil, fmt.Errorf("%w: polygon has no rings", ErrInvalidGeometry)
+		}
+		rings := make([][]Coordinates, len(polygon))
+		for j, ring := range polygon {
+			if len(ring) < 3 {
+				return nil, fmt.Errorf("%w: polygon ring has fewer than 3 positions", ErrInvalidGeometry)
+			}
+			if err := validPositions(ring); err != nil {
+				return nil, err
+			}
+			rings[j] = unwrapRing(ring)
+		}
+		prepared[i] = rings
+	}
+
+	s.indexes.mu.RLock()
+	defer s.indexes.mu.RUnlock()
+
+	found := make(map[*Airport]bool)
+	for _, rings := range prepared {
+		// The outer ring's bounds narrow the candidates to a few grid cells
+		minLat, maxLat, minLon, maxLon := ringBounds(rings[0])
+		s.indexes.spatial.searchUnwrapped(minLat, maxLat, minLon, maxLon, func(apt *Airport) {
+			if found[apt] || !filters.Matches(apt) {
+				return
+			}
+			if polygonContains(rings, apt.Lat, apt.Lon) {
+				found[apt] = true
+			}
+		})
+	}
+
+	results := make([]*Airport, 0, len(found))
+	for apt := range found {
+		results = append(results, apt)
+	}
+	sortByICAO(results)
+	return results, nil
+}
+
+// AlongRoute returns the airports matching the filters within corridorKm of
+// a route flown along great circles between consecutive positions, ordered
+// by distance along the route
+func (s *Service) AlongRoute(route []Coordinates, corridorKm float64, filters Filters, units string) ([]AirportAlongRoute, error) {
+	if len(route) < 2 {
+		return nil, fmt.Errorf("%w: route needs at least 2 positions", ErrInvalidGeometry)
+	}
+	if err := validPositions(route); err != nil {
+		return nil, err
+	}
+	if corridorKm < 0 || math.IsNaN(corridorKm) {
+		return nil, fmt.Errorf("%w: corridor must not be negative", ErrInvalidGeometry)
+	}
+
+	// Cumulative distance to the start of each leg
+	offsets := make([]float64, len(route))
+	for i := 1; i < len(route); i++ {
+		a, b := route[i-1], route[i]
+		legKm := haversine(a.Lat, a.Lon, b.Lat, b.Lon)
+		if halfCircumference-legKm < 1e-6 {
+			return nil, fmt.Errorf("%w: consecutive route positions are antipodal", ErrInvalidGeometry)
+		}
+		offsets[i] = offsets[i-1] + legKm
+	}
+
+	s.indexes.mu.RLock()
+	defer s.indexes.mu.RUnlock()
+
+	var match func(*Airport) bool
+	if !filters.IsEmpty() {
+		match = filters.Matches
+	}
+
+	// Every point within the corridor is within corridorKm plus half the
+	// sample spacing of some sample, so radius searches around the samples
+	// find every candidate
+	candidates := make(map[*Airport]bool)
+	for i := 1; i < len(route); i++ {
+		a, b := route[i-1], route[i]
+		legKm := offsets[i] - offsets[i-1]
+		samples := int(math.Ceil(legKm / math.Max(corridorKm, routeSampleMinKm)))
+		if samples < 1 {
+			samples = 1
+		}
+		radius := corridorKm + legKm/float64(samples)/2
+		for j := 0; j <= samples; j++ {
+			lat, lon := intermediatePoint(a.Lat, a.Lon, b.Lat, b.Lon, float64(j)/float64(samples))
+			for _, r := range s.indexes.spatial.withinRadius(lat, lon, radius, match) {
+				candidates[r.airport] = true
+			}
+		}
+	}
+
+	type routeMatch struct {
+		airport      *Airport
+		cross, along float64
+	}
+	matches := []routeMatch{}
+	for apt := range candidates {
+		best := routeMatch{airport: apt, cross: math.Inf(1)}
+		for i := 1; i < len(route); i++ {
+			a, b := route[i-1], route[i]
+			cross, along := legDistance(a.Lat, a.Lon, b.Lat, b.Lon, apt.Lat, apt.Lon)
+			if cross < best.cross {
+				best.cross, best.along = cross, offsets[i-1]+along
+			}
+		}
+		if best.cross <= corridorKm {
+			matches = append(matches, best)
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].along != matches[j].along {
+			return matches[i].along < matches[j].along
+		}
+		return matches[i].airport.ICAO < matches[j].airport.ICAO
+	})
+
+	results := make([]AirportAlongRoute, len(matches))
+	for i, m := range matches {
+		distance, unit := ConvertDistance(m.cross, units)
+		along, _ := ConvertDistance(m.along, units)
+		results[i] = AirportAlongRoute{
+			AirportWithDistance: AirportWithDistance{
+				Airport:      *m.airport,
+				Distance:     distance,
+				DistanceUnit: unit,
+			},
+			AlongTrack: along,
+		}
+	}
+	return results, nil
+}
+
+// validPositions checks every position is a valid latitude/longitude
+func validPositions(positions []Coordinates) error {
+	for _, p := range positions {
+		if math.IsNaN(p.Lat) || p.Lat < -90 || p.Lat > 90 {
+			return fmt.Errorf("%w: latitude %v out of range", ErrInvalidGeometry, p.Lat)
+		}
+		if math.IsNaN(p.Lon) || p.Lon < -180 || p.Lon > 180 {
+			return fmt.Errorf("%w: longitude %v out of range", ErrInvalidGeometry, p.Lon)
+		}
+	}
+	return nil
+}
+
+// unwrapRing returns a copy of the ring with longitudes made continuous, so
+// an edge crossing the antimeridian runs past 180 (or -180) instead of
+// jumping across the map
+func unwrapRing(ring []Coordinates) []Coordinates {
+	unwrapped := make([]Coordinates, len(ring))
+	unwrapped[0] = ring[0]
+	for i := 1; i < len(ring); i++ {
+		delta := ring[i].Lon - ring[i-1].Lon
+		switch {
+		case delta > 180:
+			delta -= 360
+		case delta < -180:
+			delta += 360
+		}
+		unwrapped[i] = Coordinates{Lat: ring[i].Lat, Lon: unwrapped[i-1].Lon + delta}
+	}
+	return unwrapped
+}
+
+// ringBounds returns the extent of an unwrapped ring
+func ringBounds(ring []Coordinates) (minLat, maxLat, minLon, maxLon float64) {
+	minLat, maxLat = ring[0].Lat, ring[0].Lat
+	minLon, maxLon = ring[0].Lon, ring[0].Lon
+	for _, p := range ring[1:] {
+		minLat, maxLat = math.Min(minLat, p.Lat), math.Max(maxLat, p.Lat)
+		minLon, maxLon = math.Min(minLon, p.Lon), math.Max(maxLon, p.Lon)
+	}
+	return minLat, maxLat, minLon, maxLon
+}
+
+// polygonContains reports whether a point is inside the outer ring and
+// outside every hole. Rings are unwrapped, so the point is also tried a
+// full turn east and west.
+func polygonContains(rings [][]Coordinates, lat, lon float64) bool {
+	if !ringContainsWrapped(rings[0], lat, lon) {
+		return false
+	}
+	for _, hole := range rings[1:] {
+		if ringContainsWrapped(hole, lat, lon) {
+			return false
+		}
+	}
+	return true
+}
+
+// ringContainsWrapped is ringContains for a point at any of its longitudes
+func ringContainsWrapped(ring []Coordinates, lat, lon float64) bool {
+	return ringContains(ring, lat, lon) ||
+		ringContains(ring, lat, lon+360) ||
+		ringContains(ring, lat, lon-360)
+}
+
+// ringContains reports whether a point is inside a ring by ray casting,
+// treating longitude and latitude as planar coordinates
+func ringContains(ring []Coordinates, lat, lon float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a.Lat > lat) != (b.Lat > lat) &&
+			lon < (b.Lon-a.Lon)*(lat-a.Lat)/(b.Lat-a.Lat)+a.Lon {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// searchUnwrapped is searchBox for bounds whose longitudes may run past
+// +/-180, as produced by unwrapped rings
+func (g *spatialIndex) searchUnwrapped(minLat, maxLat, minLon, maxLon float64, fn func(*Airport)) {
+	if maxLon-minLon >= 360 {
+		g.searchBox(minLat, maxLat, -180, 180, fn)
+		return
+	}
+
+	// Shift a whole number of turns so minLon is in [-180, 180)
+	turns := math.Floor((minLon + 180) / 360)
+	minLon -= turns * 360
+	maxLon -= turns * 360
+	if maxLon > 180 {
+		maxLon -= 360
+	}
+	g.searchBoxWrapped(minLat, maxLat, minLon, maxLon, fn)
+}
+
+// bearing returns the initial great-circle bearing from point 1 to point 2,
+// in radians clockwise from north
+func bearing(lat1, lon1, lat2, lon2 float64) float64 {
+	lat1Rad := lat1 * math.Pi / 180
+	lat2Rad := lat2 * math.Pi / 180
+	deltaLon := (lon2 - lon1) * math.Pi / 180
+
+	y := math.Sin(deltaLon) * math.Cos(lat2Rad)
+	x := math.Cos(lat1Rad)*math.Sin(lat2Rad) -
+		math.Sin(lat1Rad)*math.Cos(lat2Rad)*math.Cos(deltaLon)
+	return math.Atan2(y, x)
+}
+
+// intermediatePoint returns the point a fraction of the way along the great
+// circle from point 1 to point 2
+func intermediatePoint(lat1, lon1, lat2, lon2, fraction float64) (float64, float64) {
+	delta := haversine(lat1, lon1, lat2, lon2) / earthRadiusKm
+	if delta == 0 {
+		return lat1, lon1
+	}
+
+	lat1Rad, lon1Rad := lat1*math.Pi/180, lon1*math.Pi/180
+	lat2Rad, lon2Rad := lat2*math.Pi/180, lon2*math.Pi/180
+
+	a := math.Sin((1-fraction)*delta) / math.Sin(delta)
+	b := math.Sin(fraction*delta) / math.Sin(delta)
+	x := a*math.Cos(lat1Rad)*math.Cos(lon1Rad) + b*math.Cos(lat2Rad)*math.Cos(lon2Rad)
+	y := a*math.Cos(lat1Rad)*math.Sin(lon1Rad) + b*math.Cos(lat2Rad)*math.Sin(lon2Rad)
+	z := a*math.Sin(lat1Rad) + b*math.Sin(lat2Rad)
+
+	lat := math.Atan2(z, math.Sqrt(x*x+y*y)) * 180 / math.Pi
+	lon := math.Atan2(y, x) * 180 / math.Pi
+	return lat, lon
+}
+
+// legDistance returns the distance (km) from a point to the great-circle
+// leg from A to B, and how far along the leg (km) the closest point lies
+func legDistance(aLat, aLon, bLat, bLon, lat, lon float64) (cross, along float64) {
+	legKm := haversine(aLat, aLon, bLat, bLon)
+	fromA := haversine(aLat, aLon, lat, lon)
+	if legKm == 0 || fromA == 0 {
+		return fromA, 0
+	}
+
+	// Cross-track and signed along-track angles relative to the full great circle
+	delta := fromA / earthRadiusKm
+	theta := bearing(aLat, aLon, lat, lon) - bearing(aLat, aLon, bLat, bLon)
+	crossAngle := math.Asin(math.Sin(delta) * math.Sin(theta))
+	alongAngle := math.Atan2(math.Sin(delta)*math.Cos(theta), math.Cos(delta))
+
+	if alongAngle >= 0 && alongAngle*earthRadiusKm <= legKm {
+		return math.Abs(crossAngle) * earthRadiusKm, alongAngle * earthRadiusKm
+	}
+
+	// The closest point on the great circle is outside the leg, so the
+	// closest point on the leg is an end
+	if fromB := haversine(bLat, bLon, lat, lon); fromB < fromA {
+		return fromB, legKm
+	}
+	return fromA, 0
+}
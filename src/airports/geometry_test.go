@@ -0,0 +1,151 @@
+package airports
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"testing"
+)
+
+func TestWithinPolygon(t *testing.T) {
+	svc := newSyntheticService(5000)
+
+	square := []Coordinates{{30, -110}, {30, -80}, {50, -80}, {50, -110}, {30, -110}}
+	hole := []Coordinates{{35, -100}, {35, -90}, {45, -90}, {45, -100}}
+
+	got, err := svc.WithinPolygon([]Polygon{{square}}, Filters{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := bruteForceBBox(svc.data, 30, 50, -110, -80)
+	if !equalStrings(icaosOf(got), want) {
+		t.Errorf("square polygon returned %d airports, want the %d in its bounding box", len(got), len(want))
+	}
+
+	withHole, err := svc.WithinPolygon([]Polygon{{square, hole}}, Filters{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	inHole := bruteForceBBox(svc.data, 35, 45, -100, -90)
+	if len(withHole) != len(want)-len(inHole) {
+		t.Errorf("polygon with a hole returned %d airports, want %d", len(withHole), len(want)-len(inHole))
+	}
+}
+
+func TestWithinPolygonAntimeridian(t *testing.T) {
+	svc := newSyntheticService(5000)
+
+	// An unsplit ring from 170E across 180 to 170W
+	ring := []Coordinates{{-30, 170}, {-30, -170}, {0, -170}, {0, 170}}
+	got, err := svc.WithinPolygon([]Polygon{{ring}}, Filters{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := append(bruteForceBBox(svc.data, -30, 0, 170, 180), bruteForceBBox(svc.data, -30, 0, -180, -170)...)
+	sort.Strings(want)
+	if len(want) == 0 {
+		t.Fatal("expected synthetic airports near the antimeridian")
+	}
+	if !equalStrings(icaosOf(got), want) {
+		t.Errorf("antimeridian polygon returned %d airports, want %d", len(got), len(want))
+	}
+}
+
+func TestWithinPolygonInvalid(t *testing.T) {
+	svc := newSyntheticService(10)
+
+	tests := map[string][]Polygon{
+		"no polygons":     nil,
+		"no rings":        {{}},
+		"short ring":      {{{{0, 0}, {1, 1}}}},
+		"latitude range":  {{{{0, 0}, {95, 0}, {0, 1}}}},
+		"longitude range": {{{{0, 0}, {1, 190}, {0, 1}}}},
+	}
+	for name, polygons := range tests {
+		if _, err := svc.WithinPolygon(polygons, Filters{}); !errors.Is(err, ErrInvalidGeometry) {
+			t.Errorf("%s: got error %v, want ErrInvalidGeometry", name, err)
+		}
+	}
+}
+
+func TestAlongRoute(t *testing.T) {
+	svc := newSyntheticService(5000)
+
+	// Cross the dense North America cluster, then the Atlantic
+	route := []Coordinates{{35, -120}, {42, -80}, {50, 5}}
+	corridor := 150.0
+
+	got, err := svc.AlongRoute(route, corridor, Filters{}, UnitMetric)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Reference: exact distance from every airport to every leg
+	want := []string{}
+	for _, apt := range svc.data {
+		for i := 1; i < len(route); i++ {
+			cross, _ := legDistance(route[i-1].Lat, route[i-1].Lon, route[i].Lat, route[i].Lon, apt.Lat, apt.Lon)
+			if cross <= corridor {
+				want = append(want, apt.ICAO)
+				break
+			}
+		}
+	}
+	sort.Strings(want)
+
+	gotICAOs := make([]string, len(got))
+	for i, r := range got {
+		gotICAOs[i] = r.ICAO
+		if r.Distance > corridor {
+			t.Errorf("%s is %.1f km from the route, outside the corridor", r.ICAO, r.Distance)
+		}
+		if i > 0 && r.AlongTrack < got[i-1].AlongTrack {
+			t.Errorf("results not ordered along the route at %s", r.ICAO)
+		}
+	}
+	sort.Strings(gotICAOs)
+
+	if len(want) == 0 {
+		t.Fatal("expected synthetic airports along the route")
+	}
+	if !equalStrings(gotICAOs, want) {
+		t.Errorf("AlongRoute returned %d airports, linear scan found %d", len(gotICAOs), len(want))
+	}
+}
+
+func TestAlongRouteInvalid(t *testing.T) {
+	svc := newSyntheticService(10)
+
+	if _, err := svc.AlongRoute([]Coordinates{{0, 0}}, 50, Filters{}, UnitMetric); !errors.Is(err, ErrInvalidGeometry) {
+		t.Errorf("single position: got error %v, want ErrInvalidGeometry", err)
+	}
+	if _, err := svc.AlongRoute([]Coordinates{{0, 0}, {0, 180}}, 50, Filters{}, UnitMetric); !errors.Is(err, ErrInvalidGeometry) {
+		t.Errorf("antipodal leg: got error %v, want ErrInvalidGeometry", err)
+	}
+	if _, err := svc.AlongRoute([]Coordinates{{0, 0}, {1, 1}}, -1, Filters{}, UnitMetric); !errors.Is(err, ErrInvalidGeometry) {
+		t.Errorf("negative corridor: got error %v, want ErrInvalidGeometry", err)
+	}
+}
+
+func TestLegDistance(t *testing.T) {
+	degree := haversine(0, 0, 0, 1)
+
+	tests := []struct {
+		name         string
+		lat, lon     float64
+		cross, along float64
+	}{
+		{"abeam the middle", 1, 5, degree, 5 * degree},
+		{"on the leg", 0, 3, 0, 3 * degree},
+		{"before the start", 0, -2, 2 * degree, 0},
+		{"past the end", 0, 12, 2 * degree, 10 * degree},
+	}
+
+	for _, tt := range tests {
+		cross, along := legDistance(0, 0, 0, 10, tt.lat, tt.lon)
+		if math.Abs(cross-tt.cross) > 0.5 || math.Abs(along-tt.along) > 0.5 {
+			t.Errorf("%s: got cross %.1f along %.1f, want %.1f and %.1f", tt.name, cross, along, tt.cross, tt.along)
+		}
+	}
+}
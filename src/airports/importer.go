@@ -0,0 +1,469 @@
+package airports
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Import formats
+const (
+	FormatOurAirports = "ourairports" // airports.csv, runways.csv, frequencies.csv and regions.csv
+	FormatOpenFlights = "openflights" // airports.dat
+)
+
+// ImportOptions controls which rows an import keeps
+type ImportOptions struct {
+	IncludeClosed bool // Keep OurAirports airports of type "closed"
+}
+
+// SkippedRow is an input row an import did not use
+type SkippedRow struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Reason string `json:"reason"`
+}
+
+// ImportResult is the outcome of an import. Runways and frequencies are
+// attached to their airports.
+type ImportResult struct {
+	Airports AirportDatabase
+	Rows     int // Data rows read across all files
+	Skipped  []SkippedRow
+}
+
+// ErrUnknownFormat is returned for an unsupported import format
+var ErrUnknownFormat = errors.New("unknown import format (must be ourairports or openflights)")
+
+// Import reads the public dataset at path in the given format
+func Import(format, path string, opts ImportOptions) (*ImportResult, error) {
+	switch strings.ToLower(format) {
+	case FormatOurAirports:
+		return ImportOurAirports(path, opts)
+	case FormatOpenFlights:
+		return ImportOpenFlights(path)
+	}
+	return nil, ErrUnknownFormat
+}
+
+func newImportResult() *ImportResult {
+	return &ImportResult{Airports: make(AirportDatabase)}
+}
+
+func (res *ImportResult) skip(file string, line int, format string, args ...interface{}) {
+	res.Skipped = append(res.Skipped, SkippedRow{File: file, Line: line, Reason: fmt.Sprintf(format, args...)})
+}
+
+// ImportOurAirports reads the OurAirports CSV layout. path is a directory
+// holding airports.csv or the airports.csv file itself; runways.csv,
+// frequencies.csv and regions.csv next to it are read when present.
+// regions.csv supplies state names; without it states are region codes.
+func ImportOurAirports(path string, opts ImportOptions) (*ImportResult, error) {
+	dir, airportsFile := path, filepath.Join(path, "airports.csv")
+	if info, err := os.Stat(path); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		dir, airportsFile = filepath.Dir(path), path
+	}
+
+	res := newImportResult()
+
+	regions := map[string]string{}
+	if err := readOptionalCSV(filepath.Join(dir, "regions.csv"), res, func(rows *csvRows) {
+		readOurAirportsRegions(rows, regions)
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := readCSV(airportsFile, true, res, func(rows *csvRows) {
+		readOurAirportsAirports(rows, res, regions, opts)
+	}); err != nil {
+		return nil, err
+	}
+
+	// Airports are keyed by ident, which runways and frequencies refer to
+	if err := readOptionalCSV(filepath.Join(dir, "runways.csv"), res, func(rows *csvRows) {
+		readOurAirportsRunways(rows, res)
+	}); err != nil {
+		return nil, err
+	}
+	if err := readOptionalCSV(filepath.Join(dir, "frequencies.csv"), res, func(rows *csvRows) {
+		readOurAirportsFrequencies(rows, res)
+	}); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// ImportOpenFlights reads an OpenFlights airports.dat file. Rows without an
+// ICAO code are skipped, since airports are keyed by ICAO.
+func ImportOpenFlights(path string) (*ImportResult, error) {
+	res := newImportResult()
+	if err := readCSV(path, false, res, func(rows *csvRows) {
+		readOpenFlightsAirports(rows, res)
+	}); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// csvRows iterates the records of a CSV file, reporting malformed lines as
+// skipped and giving access to fields by header name
+type csvRows struct {
+	file    string
+	reader  *csv.Reader
+	res     *ImportResult
+	columns map[string]int
+	record  []string
+	line    int
+	err     error
+}
+
+// readCSV opens a CSV file and passes its rows to read. With header set
+// the first record names the columns.
+func readCSV(path string, header bool, res *ImportResult, read func(*csvRows)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.ReuseRecord = true
+
+	rows := &csvRows{file: filepath.Base(path), reader: reader, res: res}
+	if header {
+		record, err := reader.Read()
+		if err != nil {
+			return fmt.Errorf("%s: failed to read header: %w", rows.file, err)
+		}
+		rows.columns = make(map[string]int, len(record))
+		for i, name := range record {
+			rows.columns[strings.TrimPrefix(strings.TrimSpace(name), "\ufeff")] = i
+		}
+	}
+
+	read(rows)
+	return rows.err
+}
+
+// readOptionalCSV reads a headed CSV file if it exists
+func readOptionalCSV(path string, res *ImportResult, read func(*csvRows)) error {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return readCSV(path, true, res, read)
+}
+
+// next advances to the next record, skipping lines the CSV reader rejects
+func (rows *csvRows) next() bool {
+	for {
+		record, err := rows.reader.Read()
+		if err == io.EOF {
+			return false
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				rows.res.Rows++
+				rows.res.skip(rows.file, parseErr.Line, "malformed CSV: %v", parseErr.Err)
+				continue
+			}
+			rows.err = fmt.Errorf("%s: %w", rows.file, err)
+			return false
+		}
+		rows.res.Rows++
+		rows.record = record
+		rows.line, _ = rows.reader.FieldPos(0)
+		return true
+	}
+}
+
+// require reports the missing columns of a headed file as an error
+func (rows *csvRows) require(names ...string) bool {
+	for _, name := range names {
+		if _, ok := rows.columns[name]; !ok {
+			rows.err = fmt.Errorf("%s: missing column %q", rows.file, name)
+			return false
+		}
+	}
+	return true
+}
+
+// get returns a field by header name
+func (rows *csvRows) get(name string) string {
+	i, ok := rows.columns[name]
+	if !ok || i >= len(rows.record) {
+		return ""
+	}
+	return strings.TrimSpace(rows.record[i])
+}
+
+// at returns a field by index, treating OpenFlights' \N as empty
+func (rows *csvRows) at(i int) string {
+	if i >= len(rows.record) {
+		return ""
+	}
+	value := strings.TrimSpace(rows.record[i])
+	if value == `\N` {
+		return ""
+	}
+	return value
+}
+
+func (rows *csvRows) skip(format string, args ...interface{}) {
+	rows.res.skip(rows.file, rows.line, format, args...)
+}
+
+// readOurAirportsRegions maps region codes such as "US-NY" to names
+func readOurAirportsRegions(rows *csvRows, regions map[string]string) {
+	if !rows.require("code", "name") {
+		return
+	}
+	for rows.next() {
+		if code, name := rows.get("code"), rows.get("name"); code != "" && name != "" && name != "(unassigned)" {
+			regions[code] = name
+		}
+	}
+}
+
+func readOurAirportsAirports(rows *csvRows, res *ImportResult, regions map[string]string, opts ImportOptions) {
+	if !rows.require("ident", "type", "name", "latitude_deg", "longitude_deg", "iso_country") {
+		return
+	}
+	for rows.next() {
+		ident := strings.ToUpper(rows.get("ident"))
+		if ident == "" {
+			rows.skip("missing ident")
+			continue
+		}
+		if rows.get("type") == "closed" && !opts.IncludeClosed {
+			rows.skip("%s: closed", ident)
+			continue
+		}
+		if _, ok := res.Airports[ident]; ok {
+			rows.skip("%s: duplicate ident", ident)
+			continue
+		}
+		airportType, ok := ParseAirportType(rows.get("type"))
+		if !ok {
+			rows.skip("%s: unknown type %q", ident, rows.get("type"))
+			continue
+		}
+
+		lat, lon, err := parseCoordinates(rows.get("latitude_deg"), rows.get("longitude_deg"))
+		if err != nil {
+			rows.skip("%s: %v", ident, err)
+			continue
+		}
+		elevation, err := parseOptionalInt(rows.get("elevation_ft"))
+		if err != nil {
+			rows.skip("%s: invalid elevation %q", ident, rows.get("elevation_ft"))
+			continue
+		}
+
+		region := rows.get("iso_region")
+		state, ok := regions[region]
+		if !ok {
+			// "US-NY" -> "NY"; unassigned regions ("US-U-A") have no state
+			if _, code, found := strings.Cut(region, "-"); found && !strings.Contains(code, "-") {
+				state = code
+			}
+		}
+
+		country := strings.ToUpper(rows.get("iso_country"))
+		localCode := strings.ToUpper(rows.get("local_code"))
+		faa := ""
+		if country == "US" {
+			// US local codes are FAA location identifiers
+			faa = localCode
+		}
+
+		apt := Airport{
+			ICAO:      ident,
+			IATA:      strings.ToUpper(rows.get("iata_code")),
+			Name:      rows.get("name"),
+			City:      rows.get("municipality"),
+			State:     state,
+			Country:   country,
+			Elevation: elevation,
+			Lat:       lat,
+			Lon:       lon,
+			Type:      airportType,
+			FAA:       faa,
+			LocalCode: localCode,
+		}
+		if scheduled := rows.get("scheduled_service"); scheduled == "yes" || scheduled == "no" {
+			hasService := scheduled == "yes"
+			apt.ScheduledService = &hasService
+		}
+		res.Airports[ident] = apt
+	}
+}
+
+func readOurAirportsRunways(rows *csvRows, res *ImportResult) {
+	if !rows.require("airport_ident", "le_ident", "he_ident") {
+		return
+	}
+	for rows.next() {
+		ident := strings.ToUpper(rows.get("airport_ident"))
+		apt, ok := res.Airports[ident]
+		if !ok {
+			rows.skip("%s: airport not imported", ident)
+			continue
+		}
+
+		length, err := parseOptionalInt(rows.get("length_ft"))
+		if err != nil {
+			rows.skip("%s: invalid runway length %q", ident, rows.get("length_ft"))
+			continue
+		}
+		width, err := parseOptionalInt(rows.get("width_ft"))
+		if err != nil {
+			rows.skip("%s: invalid runway width %q", ident, rows.get("width_ft"))
+			continue
+		}
+		heading, _ := strconv.ParseFloat(rows.get("le_heading_degT"), 64)
+
+		runwayIdent := rows.get("le_ident")
+		if he := rows.get("he_ident"); he != "" {
+			runwayIdent += "/" + he
+		}
+		apt.Runways = append(apt.Runways, Runway{
+			Ident:    strings.Trim(runwayIdent, "/"),
+			LengthFt: length,
+			WidthFt:  width,
+			Surface:  rows.get("surface"),
+			Heading:  heading,
+			Lighted:  rows.get("lighted") == "1",
+			Closed:   rows.get("closed") == "1",
+		})
+		res.Airports[ident] = apt
+	}
+}
+
+func readOurAirportsFrequencies(rows *csvRows, res *ImportResult) {
+	if !rows.require("airport_ident", "type", "frequency_mhz") {
+		return
+	}
+	for rows.next() {
+		ident := strings.ToUpper(rows.get("airport_ident"))
+		apt, ok := res.Airports[ident]
+		if !ok {
+			rows.skip("%s: airport not imported", ident)
+			continue
+		}
+		mhz, err := strconv.ParseFloat(rows.get("frequency_mhz"), 64)
+		if err != nil || mhz <= 0 {
+			rows.skip("%s: invalid frequency %q", ident, rows.get("frequency_mhz"))
+			continue
+		}
+		apt.Frequencies = append(apt.Frequencies, Frequency{
+			Type:        strings.ToUpper(rows.get("type")),
+			Description: rows.get("description"),
+			MHz:         mhz,
+		})
+		res.Airports[ident] = apt
+	}
+}
+
+// OpenFlights airports.dat columns
+const (
+	ofName = 1 + iota
+	ofCity
+	ofCountry
+	ofIATA
+	ofICAO
+	ofLat
+	ofLon
+	ofAltitude
+	ofUTCOffset
+	ofDST
+	ofTz
+	ofType
+)
+
+func readOpenFlightsAirports(rows *csvRows, res *ImportResult) {
+	for rows.next() {
+		if len(rows.record) <= ofTz {
+			rows.skip("expected at least %d fields, got %d", ofTz+1, len(rows.record))
+			continue
+		}
+		icao := strings.ToUpper(rows.at(ofICAO))
+		if icao == "" {
+			rows.skip("%s: missing ICAO code", rows.at(ofName))
+			continue
+		}
+		if kind := rows.at(ofType); kind != "" && kind != "airport" {
+			rows.skip("%s: not an airport (%s)", icao, kind)
+			continue
+		}
+		if _, ok := res.Airports[icao]; ok {
+			rows.skip("%s: duplicate ICAO code", icao)
+			continue
+		}
+
+		lat, lon, err := parseCoordinates(rows.at(ofLat), rows.at(ofLon))
+		if err != nil {
+			rows.skip("%s: %v", icao, err)
+			continue
+		}
+		elevation, err := parseOptionalInt(rows.at(ofAltitude))
+		if err != nil {
+			rows.skip("%s: invalid altitude %q", icao, rows.at(ofAltitude))
+			continue
+		}
+		country, ok := countryCode(rows.at(ofCountry))
+		if !ok {
+			rows.skip("%s: unknown country %q", icao, rows.at(ofCountry))
+			continue
+		}
+
+		res.Airports[icao] = Airport{
+			ICAO:      icao,
+			IATA:      strings.ToUpper(rows.at(ofIATA)),
+			Name:      rows.at(ofName),
+			City:      rows.at(ofCity),
+			Country:   country,
+			Elevation: elevation,
+			Lat:       lat,
+			Lon:       lon,
+			Tz:        rows.at(ofTz),
+		}
+	}
+}
+
+// parseCoordinates parses and range-checks a latitude and longitude
+func parseCoordinates(latStr, lonStr string) (lat, lon float64, err error) {
+	lat, err = strconv.ParseFloat(latStr, 64)
+	if err != nil || math.IsNaN(lat) || lat < -90 || lat > 90 {
+		return 0, 0, fmt.Errorf("invalid latitude %q", latStr)
+	}
+	lon, err = strconv.ParseFloat(lonStr, 64)
+	if err != nil || math.IsNaN(lon) || lon < -180 || lon > 180 {
+		return 0, 0, fmt.Errorf("invalid longitude %q", lonStr)
+	}
+	return lat, lon, nil
+}
+
+// parseOptionalInt parses a whole number that may be empty or written with
+// a fractional part
+func parseOptionalInt(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	return int(math.Round(f)), nil
+}
@@ -0,0 +1,202 @@
+package airports
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeImportFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func skippedReasons(res *ImportResult) []string {
+	reasons := make([]string, len(res.Skipped))
+	for i, s := range res.Skipped {
+		reasons[i] = s.Reason
+	}
+	return reasons
+}
+
+func TestImportOurAirports(t *testing.T) {
+	dir := writeImportFiles(t, map[string]string{
+		"airports.csv": `"id","ident","type","name","latitude_deg","longitude_deg","elevation_ft","continent","iso_country","iso_region","municipality","scheduled_service","gps_code","iata_code","local_code","home_link","wikipedia_link","keywords"
+3622,"KJFK","large_airport","John F Kennedy International Airport",40.639447,-73.779317,13,"NA","US","US-NY","New York","yes","KJFK","JFK","JFK","","",""
+6523,"00A","heliport","Total RF Heliport",40.070985,-74.933689,11,"NA","US","US-PA","Bensalem","no","K00A","","00A","","",""
+1,"XCLO","closed","Closed Field",10,10,,"NA","US","US-U-A","","no","","","","","",""
+2,"XBAD","small_airport","Bad Latitude",95,10,100,"NA","US","US-NY","","no","","","","","",""
+3,"XELV","small_airport","Bad Elevation",10,10,high,"NA","US","US-NY","","no","","","","","",""
+4,"","small_airport","No Ident",10,10,1,"NA","US","US-NY","","no","","","","","",""
+5,"KJFK","large_airport","Duplicate",40,-73,1,"NA","US","US-NY","","no","","","","","",""
+`,
+		"regions.csv": `"id","code","local_code","name","continent","iso_country","wikipedia_link","keywords"
+306161,"US-NY","NY","New York","NA","US","",""
+`,
+		"runways.csv": `"id","airport_ref","airport_ident","length_ft","width_ft","surface","lighted","closed","le_ident","le_latitude_deg","le_longitude_deg","le_elevation_ft","le_heading_degT","le_displaced_threshold_ft","he_ident","he_latitude_deg","he_longitude_deg","he_elevation_ft","he_heading_degT","he_displaced_threshold_ft"
+1,3622,"KJFK",12079,200,"ASP",1,0,"04L",,,,31,,"22R",,,,211,
+2,1,"XCLO",1000,50,"TURF",0,1,"09",,,,,,"27",,,,,
+3,3622,"KJFK",long,200,"ASP",1,0,"13L",,,,,,"31R",,,,,
+`,
+		"frequencies.csv": `"id","airport_ref","airport_ident","type","description","frequency_mhz"
+1,3622,"KJFK","twr","KENNEDY TWR",119.1
+2,3622,"KJFK","GND","",
+`,
+	})
+
+	res, err := ImportOurAirports(dir, ImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res.Airports) != 2 {
+		t.Fatalf("expected 2 airports, got %d: %v", len(res.Airports), res.Airports)
+	}
+	jfk := res.Airports["KJFK"]
+	runways, frequencies := jfk.Runways, jfk.Frequencies
+	jfk.Runways, jfk.Frequencies, jfk.ScheduledService = nil, nil, nil
+	want := Airport{ICAO: "KJFK", IATA: "JFK", Name: "John F Kennedy International Airport", City: "New York",
+		State: "New York", Country: "US", Elevation: 13, Lat: 40.639447, Lon: -73.779317,
+		Type: TypeLargeAirport, FAA: "JFK", LocalCode: "JFK"}
+	if !reflect.DeepEqual(jfk, want) {
+		t.Errorf("KJFK = %+v, want %+v", jfk, want)
+	}
+	if scheduled := res.Airports["KJFK"].ScheduledService; scheduled == nil || !*scheduled {
+		t.Error("expected KJFK to have scheduled service")
+	}
+	heliport := res.Airports["00A"]
+	if heliport.State != "PA" {
+		t.Errorf("expected the region code as state without a region name, got %q", heliport.State)
+	}
+	if heliport.Type != TypeHeliport || heliport.ScheduledService == nil || *heliport.ScheduledService {
+		t.Errorf("unexpected 00A type and service: %+v", heliport)
+	}
+
+	if len(runways) != 1 || runways[0].Ident != "04L/22R" || runways[0].LengthFt != 12079 || !runways[0].Lighted || runways[0].Heading != 31 {
+		t.Errorf("unexpected KJFK runways: %+v", runways)
+	}
+	if len(frequencies) != 1 || frequencies[0].Type != "TWR" || frequencies[0].MHz != 119.1 {
+		t.Errorf("unexpected KJFK frequencies: %+v", frequencies)
+	}
+
+	// 1 region, 7 airports, 3 runways and 2 frequencies
+	if res.Rows != 13 {
+		t.Errorf("expected 13 rows read, got %d", res.Rows)
+	}
+	wantSkipped := []string{
+		"XCLO: closed",
+		`XBAD: invalid latitude "95"`,
+		`XELV: invalid elevation "high"`,
+		"missing ident",
+		"KJFK: duplicate ident",
+		"XCLO: airport not imported",
+		`KJFK: invalid runway length "long"`,
+		`KJFK: invalid frequency ""`,
+	}
+	if got := skippedReasons(res); !equalStrings(got, wantSkipped) {
+		t.Errorf("skipped\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(wantSkipped, "\n"))
+	}
+	if res.Skipped[0].File != "airports.csv" || res.Skipped[0].Line != 4 {
+		t.Errorf("expected the first skipped row at airports.csv:4, got %+v", res.Skipped[0])
+	}
+
+	res, err = ImportOurAirports(filepath.Join(dir, "airports.csv"), ImportOptions{IncludeClosed: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if closed, ok := res.Airports["XCLO"]; !ok || closed.Type != TypeClosed || len(closed.Runways) != 1 {
+		t.Error("expected the closed airport and its runway with IncludeClosed")
+	}
+}
+
+func TestImportOurAirportsMissingColumn(t *testing.T) {
+	dir := writeImportFiles(t, map[string]string{
+		"airports.csv": "\"ident\",\"name\"\n\"KJFK\",\"Kennedy\"\n",
+	})
+	if _, err := ImportOurAirports(dir, ImportOptions{}); err == nil || !strings.Contains(err.Error(), "missing column") {
+		t.Errorf("expected a missing column error, got %v", err)
+	}
+}
+
+func TestImportOpenFlights(t *testing.T) {
+	dir := writeImportFiles(t, map[string]string{
+		"airports.dat": `507,"London Heathrow Airport","London","United Kingdom","LHR","EGLL",51.4706,-0.461941,83,0,"E","Europe/London","airport","OurAirports"
+3797,"John F Kennedy International Airport","New York","United States","JFK","KJFK",40.63980103,-73.77890015,13,-5,"A","America/New_York","airport","OurAirports"
+1059,"Ndjili International Airport","Kinshasa","Congo (Kinshasa)","FIH","FZAA",-4.38575,15.4446,1027,1,"N","Africa/Kinshasa","airport","OurAirports"
+5674,"Port Bouet Airport","Abidjan","Cote d'Ivoire","ABJ","DIAP",5.261390209,-3.926290035,21,0,"N","Africa/Abidjan","airport","OurAirports"
+9999,"No ICAO","Nowhere","United States","XXX",\N,10,10,0,0,"N","\N","airport","OurAirports"
+8000,"Central Station","Berlin","Germany",\N,"XBER",52.5,13.4,0,1,"E","Europe/Berlin","station","User"
+8001,"Atlantis","Atlantis","Atlantis",\N,"XATL",0,0,0,0,"N","\N","airport","User"
+8002,"Short Row","Somewhere","Germany"
+`,
+	})
+
+	res, err := Import(FormatOpenFlights, filepath.Join(dir, "airports.dat"), ImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantCountries := map[string]string{"EGLL": "GB", "KJFK": "US", "FZAA": "CD", "DIAP": "CI"}
+	if len(res.Airports) != len(wantCountries) {
+		t.Errorf("expected %d airports, got %d", len(wantCountries), len(res.Airports))
+	}
+	for icao, country := range wantCountries {
+		if got := res.Airports[icao].Country; got != country {
+			t.Errorf("%s: country %q, want %q", icao, got, country)
+		}
+	}
+	lhr := res.Airports["EGLL"]
+	if lhr.IATA != "LHR" || lhr.Tz != "Europe/London" || lhr.Elevation != 83 || lhr.City != "London" {
+		t.Errorf("unexpected EGLL: %+v", lhr)
+	}
+
+	wantSkipped := []string{
+		"No ICAO: missing ICAO code",
+		"XBER: not an airport (station)",
+		`XATL: unknown country "Atlantis"`,
+		"expected at least 12 fields, got 4",
+	}
+	if got := skippedReasons(res); !equalStrings(got, wantSkipped) {
+		t.Errorf("skipped\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(wantSkipped, "\n"))
+	}
+}
+
+func TestImportUnknownFormat(t *testing.T) {
+	if _, err := Import("csv", "airports.csv", ImportOptions{}); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("expected ErrUnknownFormat, got %v", err)
+	}
+}
+
+func TestCountryCode(t *testing.T) {
+	tests := map[string]string{
+		"United States":                    "US",
+		"united kingdom":                   "GB",
+		"Congo (Brazzaville)":              "CG",
+		"Burma":                            "MM",
+		"Myanmar":                          "MM",
+		"Saint Kitts and Nevis":            "KN",
+		"Saint Vincent and the Grenadines": "VC",
+		"Bosnia and Herzegovina":           "BA",
+		"Reunion":                          "RE",
+		"Hong Kong":                        "HK",
+		"Czech Republic":                   "CZ",
+		"de":                               "DE",
+	}
+	for name, want := range tests {
+		if got, ok := countryCode(name); !ok || got != want {
+			t.Errorf("countryCode(%q) = %q, %v, want %q", name, got, ok, want)
+		}
+	}
+	if _, ok := countryCode("Atlantis"); ok {
+		t.Error("expected Atlantis to be unknown")
+	}
+}
@@ -0,0 +1,193 @@
+package airports
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Sort fields accepted by List and SearchPage
+const (
+	SortICAO      = "icao"
+	SortIATA      = "iata"
+	SortName      = "name"
+	SortElevation = "elevation"
+	SortCountry   = "country"
+	SortRelevance = "relevance" // Default when a search query is given
+)
+
+// ErrInvalidSort is returned for an unknown sort field
+var ErrInvalidSort = errors.New("invalid sort field")
+
+// sortKeys map each field sort to a key that orders airports ascending by
+// that field. Keys end with the ICAO code so they are unique.
+var sortKeys = map[string]func(*Airport) string{
+	SortICAO: icaoKey,
+	SortIATA: func(apt *Airport) string {
+		return apt.IATA + "\x00" + apt.ICAO
+	},
+	SortName: func(apt *Airport) string {
+		return apt.Name + "\x00" + apt.ICAO
+	},
+	SortElevation: func(apt *Airport) string {
+		// Offset so negative elevations sort as fixed-width strings
+		return fmt.Sprintf("%08d\x00%s", apt.Elevation+50000, apt.ICAO)
+	},
+	SortCountry: func(apt *Airport) string {
+		return apt.Country + "\x00" + apt.ICAO
+	},
+}
+
+// ListOptions selects, orders and pages the airports returned by List and
+// SearchPage
+type ListOptions struct {
+	Query   string // Search query; empty lists every airport matching Filters
+	Filters Filters
+	Sort    string // Sort field (default icao, or relevance with a Query)
+	Desc    bool   // Reverse the sort order
+	Page    Page
+}
+
+// ValidSort reports whether field is an accepted sort field (empty selects the default)
+func ValidSort(field string) bool {
+	_, ok := sortKeys[field]
+	return ok || field == "" || field == SortRelevance
+}
+
+// ranked is an airport with its search score
+type ranked struct {
+	airport *Airport
+	score   float64
+}
+
+// List returns a page of airports matching the options
+func (s *Service) List(opts ListOptions) ([]*Airport, PageInfo, error) {
+	s.indexes.mu.RLock()
+	defer s.indexes.mu.RUnlock()
+
+	return s.list(opts)
+}
+
+// ListAll returns every airport matching the options, ignoring the page
+func (s *Service) ListAll(opts ListOptions) ([]*Airport, error) {
+	s.indexes.mu.RLock()
+	defer s.indexes.mu.RUnlock()
+
+	opts.Page = Page{Limit: len(s.indexes.sorted)}
+	airports, _, err := s.list(opts)
+	return airports, err
+}
+
+// list implements List. The caller holds the read lock.
+func (s *Service) list(opts ListOptions) ([]*Airport, PageInfo, error) {
+	if opts.Query != "" {
+		results, info, err := s.searchPage(opts)
+		airports := make([]*Airport, len(results))
+		for i, r := range results {
+			airports[i] = r.airport
+		}
+		return airports, info, err
+	}
+
+	field := strings.ToLower(opts.Sort)
+	if field == "" || field == SortRelevance {
+		field = SortICAO
+	}
+	key, ok := sortKeys[field]
+	if !ok {
+		return nil, PageInfo{}, ErrInvalidSort
+	}
+
+	// Start from the narrowest index already in the requested order
+	candidates := s.indexes.ordered[field]
+	if field == SortICAO {
+		candidates = s.indexes.sorted
+		switch {
+		case opts.Filters.Country != "":
+			candidates = s.indexes.ByCountry[strings.ToUpper(strings.TrimSpace(opts.Filters.Country))]
+		case opts.Filters.State != "":
+			candidates = s.indexes.ByState[normalizeText(strings.TrimSpace(opts.Filters.State))]
+		case len(opts.Filters.Types) == 1:
+			candidates = s.indexes.ByType[opts.Filters.Types[0]]
+		}
+	}
+
+	if !opts.Filters.IsEmpty() {
+		matched := make([]*Airport, 0, len(candidates))
+		for _, apt := range candidates {
+			if opts.Filters.Matches(apt) {
+				matched = append(matched, apt)
+			}
+		}
+		candidates = matched
+	}
+
+	return paginate(candidates, opts.Page, key, opts.Desc)
+}
+
+// SearchPage returns a page of airports matching the search query and
+// filters, with relevance scores
+func (s *Service) SearchPage(opts ListOptions) ([]SearchResult, PageInfo, error) {
+	s.indexes.mu.RLock()
+	defer s.indexes.mu.RUnlock()
+
+	results, info, err := s.searchPage(opts)
+	if err != nil {
+		return nil, info, err
+	}
+
+	page := make([]SearchResult, len(results))
+	for i, r := range results {
+		page[i] = SearchResult{Airport: *r.airport, Score: r.score}
+	}
+	return page, info, nil
+}
+
+// searchPage filters, orders and pages search matches. The caller holds the read lock.
+func (s *Service) searchPage(opts ListOptions) ([]ranked, PageInfo, error) {
+	field := strings.ToLower(opts.Sort)
+	if !ValidSort(field) {
+		return nil, PageInfo{}, ErrInvalidSort
+	}
+
+	results := s.rankedSearch(opts.Query)
+	if !opts.Filters.IsEmpty() {
+		matched := results[:0]
+		for _, r := range results {
+			if opts.Filters.Matches(r.airport) {
+				matched = append(matched, r)
+			}
+		}
+		results = matched
+	}
+
+	key := searchKey
+	if fieldKey, ok := sortKeys[field]; ok {
+		key = func(r ranked) string {
+			return fieldKey(r.airport)
+		}
+		results = sortedByKey(results, key)
+	}
+
+	return paginate(results, opts.Page, key, opts.Desc)
+}
+
+// sortedByKey returns a copy of items ordered ascending by key
+func sortedByKey[T any](items []T, key func(T) string) []T {
+	keys := make([]string, len(items))
+	order := make([]int, len(items))
+	for i, item := range items {
+		keys[i] = key(item)
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return keys[order[i]] < keys[order[j]]
+	})
+
+	sorted := make([]T, len(items))
+	for i, idx := range order {
+		sorted[i] = items[idx]
+	}
+	return sorted
+}
@@ -0,0 +1,143 @@
+package airports
+
+import "testing"
+
+func TestListSort(t *testing.T) {
+	svc := newSearchTestService()
+	svc.data["EGLL"] = Airport{ICAO: "EGLL", IATA: "LHR", Name: "London Heathrow Airport", City: "London", Country: "GB", Elevation: 83}
+	svc.data["KLHR"] = Airport{ICAO: "KLHR", Name: "Heathrow Field", City: "Lamar", Country: "US", Elevation: -12}
+	svc.data["SBGR"] = Airport{ICAO: "SBGR", IATA: "GRU", Name: "Guarulhos International Airport", City: "Sao Paulo", Country: "BR", Elevation: 2459}
+	svc.indexes = BuildIndexes(svc.data)
+
+	list, _, err := svc.List(ListOptions{Sort: SortElevation, Desc: true, Page: Page{Limit: 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The remaining airports are at 0 ft, so ties break by ICAO in reverse
+	if got := icaosOf(list); !equalStrings(got, []string{"SBGR", "EGLL", "KLGA"}) {
+		t.Errorf("elevation desc = %v, want [SBGR EGLL KLGA]", got)
+	}
+
+	list, _, _ = svc.List(ListOptions{Sort: SortElevation, Page: Page{Limit: 1}})
+	if list[0].ICAO != "KLHR" {
+		t.Errorf("lowest elevation = %s, want KLHR (negative elevation)", list[0].ICAO)
+	}
+
+	list, _, _ = svc.List(ListOptions{Sort: SortName, Page: Page{Limit: 100}})
+	for i := 1; i < len(list); i++ {
+		if list[i-1].Name > list[i].Name {
+			t.Errorf("name sort: %q before %q", list[i-1].Name, list[i].Name)
+		}
+	}
+
+	if _, _, err := svc.List(ListOptions{Sort: "bogus"}); err != ErrInvalidSort {
+		t.Errorf("err = %v, want ErrInvalidSort", err)
+	}
+}
+
+func TestListDescendingCursor(t *testing.T) {
+	svc := newSyntheticService(200)
+
+	all, _, _ := svc.List(ListOptions{Sort: SortName, Desc: true, Page: Page{Limit: 200}})
+
+	var paged []*Airport
+	page := Page{Limit: 30}
+	for {
+		list, info, err := svc.List(ListOptions{Sort: SortName, Desc: true, Page: page})
+		if err != nil {
+			t.Fatal(err)
+		}
+		paged = append(paged, list...)
+		if info.NextCursor == "" {
+			break
+		}
+		page.Cursor = info.NextCursor
+	}
+
+	if !equalStrings(icaosOf(paged), icaosOf(all)) {
+		t.Errorf("descending cursor pages differ from a single page")
+	}
+}
+
+func TestListFilterCombinations(t *testing.T) {
+	svc := newSearchTestService()
+	yes := true
+	minElev := 0
+
+	tests := []struct {
+		name string
+		opts ListOptions
+		want int
+	}{
+		{"country and IATA", ListOptions{Filters: Filters{Country: "GB", HasIATA: &yes}}, 3},
+		{"name prefix", ListOptions{Filters: Filters{NamePrefix: "london h"}}, 2},
+		{"elevation range", ListOptions{Filters: Filters{MinElevation: &minElev}}, 9},
+		{"search with filter", ListOptions{Query: "london", Filters: Filters{Country: "CA"}}, 1},
+		{"search sorted by ICAO", ListOptions{Query: "london", Sort: SortICAO}, 5},
+		{"state", ListOptions{Filters: Filters{State: "ONTARIO"}}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Page = Page{Limit: 100}
+			_, info, err := svc.List(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Total != tt.want {
+				t.Errorf("total = %d, want %d", info.Total, tt.want)
+			}
+		})
+	}
+
+	list, _, _ := svc.List(ListOptions{Query: "london", Sort: SortICAO, Page: Page{Limit: 100}})
+	if list[0].ICAO != "CYXU" {
+		t.Errorf("first by ICAO = %s, want CYXU", list[0].ICAO)
+	}
+}
+
+func TestListTypeAndRunwayFilters(t *testing.T) {
+	yes, no := true, false
+	data := AirportDatabase{
+		"KJFK": {ICAO: "KJFK", Type: TypeLargeAirport, ScheduledService: &yes, Runways: []Runway{
+			{Ident: "04L/22R", LengthFt: 12079, Surface: "ASP"},
+			{Ident: "13R/31L", LengthFt: 14511, Surface: "CON"},
+		}},
+		"KFRG": {ICAO: "KFRG", Type: TypeMediumAirport, ScheduledService: &no, Runways: []Runway{
+			{Ident: "01/19", LengthFt: 6833, Surface: "ASP"},
+			{Ident: "14/32", LengthFt: 7000, Surface: "CON", Closed: true},
+		}},
+		"00A":  {ICAO: "00A", Type: TypeHeliport, ScheduledService: &no, Runways: []Runway{{Ident: "H1", LengthFt: 80, Surface: "ASP"}}},
+		"XCLO": {ICAO: "XCLO", Type: TypeClosed},
+		"EGLL": {ICAO: "EGLL"},
+	}
+	svc := &Service{data: data, indexes: BuildIndexes(data)}
+	minRunway := 7000
+
+	tests := []struct {
+		name    string
+		filters Filters
+		want    []string
+	}{
+		{"one type", Filters{Types: []string{TypeHeliport}}, []string{"00A"}},
+		{"several types", Filters{Types: []string{TypeLargeAirport, TypeMediumAirport}}, []string{"KFRG", "KJFK"}},
+		{"excluded types", Filters{ExcludeTypes: []string{TypeHeliport, TypeClosed}}, []string{"EGLL", "KFRG", "KJFK"}},
+		{"scheduled service", Filters{ScheduledService: &yes}, []string{"KJFK"}},
+		{"no scheduled service", Filters{ScheduledService: &no}, []string{"00A", "KFRG"}},
+		{"runway length ignores closed runways", Filters{MinRunwayLength: &minRunway}, []string{"KJFK"}},
+		{"runway surface", Filters{RunwaySurface: "con"}, []string{"KJFK"}},
+		{"length and surface on one runway", Filters{MinRunwayLength: &minRunway, RunwaySurface: "ASP"}, []string{"KJFK"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, _, err := svc.List(ListOptions{Filters: tt.filters, Page: Page{Limit: 100}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := icaosOf(list); !equalStrings(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
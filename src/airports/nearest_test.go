@@ -0,0 +1,44 @@
+package airports
+
+import "testing"
+
+func TestNearestHasNoRadiusCap(t *testing.T) {
+	svc := newSyntheticService(2000)
+
+	// Points far from any airport must still return k results
+	for _, p := range [][2]float64{{-48.9, -123.4}, {-89.9, 45}, {0, -179.99}} {
+		results := svc.Nearest(p[0], p[1], 4, Filters{}, UnitMetric)
+		if len(results) != 4 {
+			t.Fatalf("Nearest(%v) returned %d airports, want 4", p, len(results))
+		}
+		for i := 1; i < len(results); i++ {
+			if results[i].Distance < results[i-1].Distance {
+				t.Errorf("Nearest(%v) not sorted by distance", p)
+			}
+		}
+		if results[0].DistanceUnit != "km" {
+			t.Errorf("Expected km distance unit, got %s", results[0].DistanceUnit)
+		}
+	}
+}
+
+func TestNearestFilters(t *testing.T) {
+	data := AirportDatabase{
+		"KJFK": {ICAO: "KJFK", IATA: "JFK", Country: "US", State: "New-York", Lat: 40.6398, Lon: -73.7789},
+		"KLGA": {ICAO: "KLGA", IATA: "LGA", Country: "US", State: "New-York", Lat: 40.7772, Lon: -73.8726},
+		"00NY": {ICAO: "00NY", Country: "US", State: "New-York", Lat: 40.7, Lon: -73.8},
+		"EGLL": {ICAO: "EGLL", IATA: "LHR", Country: "GB", State: "England", Lat: 51.4706, Lon: -0.4619},
+	}
+	svc := &Service{data: data, indexes: BuildIndexes(data)}
+
+	noIATA := false
+	results := svc.Nearest(40.7, -73.8, 1, Filters{HasIATA: &noIATA}, UnitMetric)
+	if len(results) != 1 || results[0].ICAO != "00NY" {
+		t.Errorf("Expected 00NY for has_iata=false, got %v", results)
+	}
+
+	results = svc.Nearest(40.7, -73.8, 5, Filters{Country: "gb"}, UnitMetric)
+	if len(results) != 1 || results[0].ICAO != "EGLL" {
+		t.Errorf("Expected only EGLL for country=gb, got %v", results)
+	}
+}
@@ -0,0 +1,61 @@
+package airports
+
+import "testing"
+
+func TestNormalizeText(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"New York", "new york"},
+		{"São Paulo", "sao paulo"},
+		{"Zürich", "zurich"},
+		{"Düsseldorf", "dusseldorf"},
+		{"Kraków", "krakow"},
+		{"Reykjavík", "reykjavik"},
+		{"Łódź", "lodz"},
+		{"Øresund", "oresund"},
+		{"Straße", "strasse"},
+		{"Москва", "moskva"},
+		{"Αθήνα", "athina"},
+		{"ＡＢＣ", "abc"},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeText(tt.in); got != tt.want {
+			t.Errorf("normalizeText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestAccentInsensitiveLookups(t *testing.T) {
+	data := AirportDatabase{
+		"SBGR": {ICAO: "SBGR", IATA: "GRU", Name: "Guarulhos International Airport", City: "São Paulo", State: "São Paulo", Country: "BR"},
+		"LSZH": {ICAO: "LSZH", IATA: "ZRH", Name: "Zürich Airport", City: "Zürich", State: "Zurich", Country: "CH"},
+		"EDDL": {ICAO: "EDDL", IATA: "DUS", Name: "Düsseldorf International Airport", City: "Düsseldorf", Country: "DE"},
+	}
+	svc := &Service{data: data, indexes: BuildIndexes(data)}
+
+	if got := svc.GetByCity("Sao Paulo"); len(got) != 1 || got[0].ICAO != "SBGR" {
+		t.Errorf("GetByCity(Sao Paulo) = %v, want SBGR", got)
+	}
+	if got := svc.GetByCity("ZÜRICH"); len(got) != 1 || got[0].ICAO != "LSZH" {
+		t.Errorf("GetByCity(ZÜRICH) = %v, want LSZH", got)
+	}
+	if got := svc.GetByState("sao paulo"); len(got) != 1 {
+		t.Errorf("GetByState(sao paulo) returned %d airports, want 1", len(got))
+	}
+
+	for query, want := range map[string]string{
+		"Sao Paulo":   "SBGR",
+		"Zurich":      "LSZH",
+		"Dusseldorf":  "EDDL",
+		"Duesseldorf": "EDDL", // German ue spelling is within fuzzy distance
+		"düss":        "EDDL",
+	} {
+		results, _ := svc.Search(query, 10, 0)
+		if len(results) == 0 || results[0].ICAO != want {
+			t.Errorf("Search(%q) did not return %s first", query, want)
+		}
+	}
+}
@@ -0,0 +1,368 @@
+package airports
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Override actions
+const (
+	OverrideReplace = "replace" // Fields hold the whole airport
+	OverridePatch   = "patch"   // Fields hold changes to the dataset's airport
+	OverrideDelete  = "delete"  // The airport is hidden
+)
+
+// Override is an administrator's correction to one airport. Overrides are
+// kept apart from the dataset and merged over it whenever it loads, so
+// they survive reloads and dataset updates.
+type Override struct {
+	ICAO      string          `json:"icao"`
+	Action    string          `json:"action"`
+	Fields    json.RawMessage `json:"fields,omitempty"` // Airport JSON
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// OverrideChange describes an override change about to be applied
+type OverrideChange struct {
+	ICAO     string
+	Override *Override // The new override; nil when it is removed
+	Before   *Airport  // The airport served before the change; nil when absent
+	After    *Airport  // The airport served after the change; nil when absent
+}
+
+// Override errors
+var (
+	ErrAirportExists = errors.New("airport already exists")
+	ErrNoOverride    = errors.New("airport has no override")
+)
+
+// FieldError reports an invalid airport field
+type FieldError struct {
+	Field   string // JSON field name, if known
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return e.Message
+}
+
+// Validate checks that an airport has what every endpoint relies on: an
+// ICAO code, a name and coordinates in range
+func (a *Airport) Validate() error {
+	switch {
+	case strings.TrimSpace(a.ICAO) == "":
+		return &FieldError{Field: "icao", Message: "icao is required"}
+	case strings.TrimSpace(a.Name) == "":
+		return &FieldError{Field: "name", Message: "name is required"}
+	case math.IsNaN(a.Lat) || a.Lat < -90 || a.Lat > 90:
+		return &FieldError{Field: "lat", Message: "lat must be between -90 and 90"}
+	case math.IsNaN(a.Lon) || a.Lon < -180 || a.Lon > 180:
+		return &FieldError{Field: "lon", Message: "lon must be between -180 and 180"}
+	}
+	if _, ok := typeRanks[a.Type]; !ok {
+		return &FieldError{Field: "type", Message: fmt.Sprintf("type must be one of %s", strings.Join(AirportTypes, ", "))}
+	}
+	for _, rwy := range a.Runways {
+		if rwy.LengthFt < 0 || rwy.WidthFt < 0 {
+			return &FieldError{Field: "runways", Message: "runway dimensions must not be negative"}
+		}
+	}
+	return nil
+}
+
+// apply returns the airport an override produces from the dataset's
+// airport (nil when absent), or nil when the override hides it
+func (o Override) apply(base *Airport) (*Airport, error) {
+	var apt *Airport
+	var err error
+	switch o.Action {
+	case OverrideDelete:
+		return nil, nil
+	case OverrideReplace:
+		apt, err = decodeAirport(o.Fields)
+	case OverridePatch:
+		if base == nil {
+			return nil, ErrAirportNotFound
+		}
+		apt, err = patchAirport(base, o.Fields)
+	default:
+		return nil, fmt.Errorf("unknown override action %q", o.Action)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if apt.ICAO != "" && !strings.EqualFold(apt.ICAO, o.ICAO) {
+		return nil, &FieldError{Field: "icao", Message: "icao must match the airport being changed"}
+	}
+	apt.ICAO = o.ICAO
+	if err := apt.Validate(); err != nil {
+		return nil, err
+	}
+	return apt, nil
+}
+
+// decodeAirport parses airport JSON, rejecting unknown fields
+func decodeAirport(raw []byte) (*Airport, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+
+	var apt Airport
+	if err := dec.Decode(&apt); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return nil, &FieldError{Field: typeErr.Field, Message: fmt.Sprintf("invalid value for %s", typeErr.Field)}
+		}
+		return nil, &FieldError{Message: "invalid airport: " + strings.TrimPrefix(err.Error(), "json: ")}
+	}
+	return &apt, nil
+}
+
+// patchAirport sets the fields in a JSON object on a copy of an airport.
+// A null field is cleared.
+func patchAirport(apt *Airport, fields json.RawMessage) (*Airport, error) {
+	current, err := json.Marshal(apt)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := mergeFields(current, fields)
+	if err != nil {
+		return nil, err
+	}
+	return decodeAirport(merged)
+}
+
+// mergeFields combines two JSON objects, with fields in b taking precedence
+func mergeFields(a, b json.RawMessage) (json.RawMessage, error) {
+	values := map[string]json.RawMessage{}
+	if len(a) > 0 {
+		if err := json.Unmarshal(a, &values); err != nil {
+			return nil, err
+		}
+	}
+	var changes map[string]json.RawMessage
+	if err := json.Unmarshal(b, &changes); err != nil || changes == nil {
+		return nil, &FieldError{Message: "airport fields must be a JSON object"}
+	}
+	for field, value := range changes {
+		values[field] = value
+	}
+	return json.Marshal(values)
+}
+
+// mergeOverrides applies overrides over a dataset. Without overrides the
+// dataset itself is returned.
+func mergeOverrides(base AirportDatabase, overrides map[string]Override) AirportDatabase {
+	if len(overrides) == 0 {
+		return base
+	}
+
+	data := make(AirportDatabase, len(base)+len(overrides))
+	for icao, apt := range base {
+		data[icao] = apt
+	}
+	for icao, o := range overrides {
+		apt, err := o.apply(baseAirport(base, icao))
+		if err != nil {
+			// e.g. a patch for an airport the dataset no longer has
+			log.Printf("Airports: skipping override of %s: %v", icao, err)
+			continue
+		}
+		if apt == nil {
+			delete(data, icao)
+		} else {
+			data[icao] = *apt
+		}
+	}
+	return data
+}
+
+// baseAirport returns a copy of the dataset's airport, or nil
+func baseAirport(base AirportDatabase, icao string) *Airport {
+	if apt, ok := base[icao]; ok {
+		return &apt
+	}
+	return nil
+}
+
+// Overrides returns the overrides in effect, ordered by ICAO code
+func (s *Service) Overrides() []Override {
+	s.indexes.mu.RLock()
+	defer s.indexes.mu.RUnlock()
+
+	list := make([]Override, 0, len(s.overrides))
+	for _, o := range s.overrides {
+		list = append(list, o)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ICAO < list[j].ICAO })
+	return list
+}
+
+// LoadOverrides replaces every override and reindexes, as when the server
+// starts. Overrides that do not apply to the dataset are logged and skipped.
+func (s *Service) LoadOverrides(list []Override) {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	overrides := make(map[string]Override, len(list))
+	for _, o := range list {
+		o.ICAO = strings.ToUpper(o.ICAO)
+		overrides[o.ICAO] = o
+	}
+	if len(overrides) == 0 && len(s.overrides) == 0 {
+		return
+	}
+	s.applyOverrides(overrides)
+}
+
+// CreateAirport adds an airport the dataset does not serve. fields is the
+// airport's JSON. save runs before the change is applied and can veto it,
+// so callers can persist the override and its history.
+func (s *Service) CreateAirport(icao string, fields json.RawMessage, save func(OverrideChange) error) (OverrideChange, error) {
+	return s.changeOverride(icao, func(current *Override, served *Airport) (*Override, error) {
+		if served != nil {
+			return nil, ErrAirportExists
+		}
+		return &Override{Action: OverrideReplace, Fields: fields}, nil
+	}, save)
+}
+
+// ReplaceAirport sets every field of an airport, adding it if needed
+func (s *Service) ReplaceAirport(icao string, fields json.RawMessage, save func(OverrideChange) error) (OverrideChange, error) {
+	return s.changeOverride(icao, func(current *Override, served *Airport) (*Override, error) {
+		return &Override{Action: OverrideReplace, Fields: fields}, nil
+	}, save)
+}
+
+// PatchAirport changes the given fields of a served airport. Patches of
+// dataset airports accumulate, so fields they leave alone keep following
+// dataset updates.
+func (s *Service) PatchAirport(icao string, fields json.RawMessage, save func(OverrideChange) error) (OverrideChange, error) {
+	return s.changeOverride(icao, func(current *Override, served *Airport) (*Override, error) {
+		if served == nil {
+			return nil, ErrAirportNotFound
+		}
+		if current != nil && current.Action == OverrideReplace {
+			merged, err := mergeFields(current.Fields, fields)
+			if err != nil {
+				return nil, err
+			}
+			return &Override{Action: OverrideReplace, Fields: merged}, nil
+		}
+
+		var previous json.RawMessage
+		if current != nil {
+			previous = current.Fields
+		}
+		merged, err := mergeFields(previous, fields)
+		if err != nil {
+			return nil, err
+		}
+		return &Override{Action: OverridePatch, Fields: merged}, nil
+	}, save)
+}
+
+// DeleteAirport hides a served airport
+func (s *Service) DeleteAirport(icao string, save func(OverrideChange) error) (OverrideChange, error) {
+	return s.changeOverride(icao, func(current *Override, served *Airport) (*Override, error) {
+		if served == nil {
+			return nil, ErrAirportNotFound
+		}
+		return &Override{Action: OverrideDelete}, nil
+	}, save)
+}
+
+// RevertAirport removes an airport's override, serving the dataset's
+// version again
+func (s *Service) RevertAirport(icao string, save func(OverrideChange) error) (OverrideChange, error) {
+	return s.changeOverride(icao, func(current *Override, served *Airport) (*Override, error) {
+		if current == nil {
+			return nil, ErrNoOverride
+		}
+		return nil, nil
+	}, save)
+}
+
+// changeOverride replaces an airport's override with the one next derives
+// from the current override and served airport (nil removes it), lets save
+// persist the change and swaps in the reindexed dataset
+func (s *Service) changeOverride(icao string, next func(current *Override, served *Airport) (*Override, error), save func(OverrideChange) error) (OverrideChange, error) {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	icao = strings.ToUpper(strings.TrimSpace(icao))
+	if icao == "" {
+		return OverrideChange{}, &FieldError{Field: "icao", Message: "icao is required"}
+	}
+
+	// The dataset and overrides only change under reloadMu, which is held
+	var current *Override
+	if o, ok := s.overrides[icao]; ok {
+		current = &o
+	}
+	change := OverrideChange{ICAO: icao, Before: baseAirport(s.data, icao)}
+
+	o, err := next(current, change.Before)
+	if err != nil {
+		return OverrideChange{}, err
+	}
+	if o != nil {
+		o.ICAO = icao
+		o.UpdatedAt = time.Now().UTC()
+		if change.After, err = o.apply(baseAirport(s.base, icao)); err != nil {
+			return OverrideChange{}, err
+		}
+		if o.Action == OverrideReplace {
+			// Store the airport as served, in canonical form
+			if o.Fields, err = json.Marshal(change.After); err != nil {
+				return OverrideChange{}, err
+			}
+		}
+	} else {
+		change.After = baseAirport(s.base, icao)
+	}
+	change.Override = o
+
+	if save != nil {
+		if err := save(change); err != nil {
+			return OverrideChange{}, err
+		}
+	}
+
+	overrides := make(map[string]Override, len(s.overrides)+1)
+	for k, v := range s.overrides {
+		overrides[k] = v
+	}
+	if o != nil {
+		overrides[icao] = *o
+	} else {
+		delete(overrides, icao)
+	}
+	s.applyOverrides(overrides)
+
+	return change, nil
+}
+
+// applyOverrides merges overrides over the dataset and swaps in the result.
+// The caller holds reloadMu.
+func (s *Service) applyOverrides(overrides map[string]Override) {
+	data := mergeOverrides(s.base, overrides)
+	indexes := BuildIndexes(data)
+	snap := newSnapshot(data)
+
+	s.indexes.mu.Lock()
+	defer s.indexes.mu.Unlock()
+
+	s.indexes.replace(indexes)
+	s.data = data
+	s.overrides = overrides
+	s.generation++
+	s.recordVersion(snap, time.Now())
+}
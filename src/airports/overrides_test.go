@@ -0,0 +1,145 @@
+package airports
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const overrideTestData = `{
+	"KJFK": {"icao": "KJFK", "iata": "JFK", "name": "John F Kennedy International Airport", "city": "New York", "country": "US", "lat": 40.6398, "lon": -73.7789},
+	"KLGA": {"icao": "KLGA", "iata": "LGA", "name": "La Guardia Airport", "city": "New York", "country": "US", "lat": 40.7772, "lon": -73.8726}
+}`
+
+func newOverrideTestService(t *testing.T) *Service {
+	t.Helper()
+	svc, err := NewService([]byte(overrideTestData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return svc
+}
+
+func TestPatchAirport(t *testing.T) {
+	svc := newOverrideTestService(t)
+	generation := svc.Generation()
+
+	change, err := svc.PatchAirport("kjfk", json.RawMessage(`{"name": "Kennedy"}`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change.Before.Name != "John F Kennedy International Airport" || change.After.Name != "Kennedy" {
+		t.Errorf("unexpected change: %+v -> %+v", change.Before, change.After)
+	}
+	if _, err := svc.PatchAirport("KJFK", json.RawMessage(`{"lat": 40.64}`), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both patches apply, and the indexes see them
+	apt, err := svc.GetByCode("JFK")
+	if err != nil || apt.Name != "Kennedy" || apt.Lat != 40.64 {
+		t.Errorf("expected both patches, got %+v %v", apt, err)
+	}
+	if results, _ := svc.Search("kennedy", 10, 0); len(results) != 1 {
+		t.Errorf("expected the new name to be searchable, got %d results", len(results))
+	}
+	if svc.Generation() != generation+2 {
+		t.Errorf("generation = %d, want %d", svc.Generation(), generation+2)
+	}
+
+	overrides := svc.Overrides()
+	if len(overrides) != 1 || overrides[0].Action != OverridePatch {
+		t.Fatalf("expected one patch override, got %+v", overrides)
+	}
+
+	var fieldErr *FieldError
+	if _, err := svc.PatchAirport("KJFK", json.RawMessage(`{"lat": 91}`), nil); !errors.As(err, &fieldErr) || fieldErr.Field != "lat" {
+		t.Errorf("expected a lat field error, got %v", err)
+	}
+	if _, err := svc.PatchAirport("KJFK", json.RawMessage(`{"runway": []}`), nil); !errors.As(err, &fieldErr) {
+		t.Errorf("expected an unknown field error, got %v", err)
+	}
+	if _, err := svc.PatchAirport("KJFK", json.RawMessage(`{"icao": "KLGA"}`), nil); !errors.As(err, &fieldErr) || fieldErr.Field != "icao" {
+		t.Errorf("expected an icao field error, got %v", err)
+	}
+	if _, err := svc.PatchAirport("XXXX", json.RawMessage(`{"name": "Nowhere"}`), nil); !errors.Is(err, ErrAirportNotFound) {
+		t.Errorf("expected ErrAirportNotFound, got %v", err)
+	}
+}
+
+func TestCreateDeleteRevertAirport(t *testing.T) {
+	svc := newOverrideTestService(t)
+
+	fields := json.RawMessage(`{"name": "Test Field", "country": "US", "lat": 40.7, "lon": -73.9, "type": "small_airport"}`)
+	if _, err := svc.CreateAirport("KJFK", fields, nil); !errors.Is(err, ErrAirportExists) {
+		t.Errorf("expected ErrAirportExists, got %v", err)
+	}
+	change, err := svc.CreateAirport("XTST", fields, nil)
+	if err != nil || change.Before != nil || change.After.ICAO != "XTST" {
+		t.Fatalf("unexpected create: %+v %v", change, err)
+	}
+	if got := svc.GetNearby(40.7, -73.9, 1, 10); len(got) != 1 || got[0].ICAO != "XTST" {
+		t.Errorf("expected the new airport in the spatial index, got %v", got)
+	}
+
+	change, err = svc.DeleteAirport("KLGA", nil)
+	if err != nil || change.After != nil {
+		t.Fatalf("unexpected delete: %+v %v", change, err)
+	}
+	if _, err := svc.GetByCode("LGA"); err == nil {
+		t.Error("expected the deleted airport to be gone")
+	}
+	if _, err := svc.DeleteAirport("KLGA", nil); !errors.Is(err, ErrAirportNotFound) {
+		t.Errorf("expected ErrAirportNotFound deleting twice, got %v", err)
+	}
+
+	// A failed save leaves everything as it was
+	saveErr := errors.New("disk full")
+	if _, err := svc.RevertAirport("KLGA", func(OverrideChange) error { return saveErr }); !errors.Is(err, saveErr) {
+		t.Errorf("expected the save error, got %v", err)
+	}
+	if _, err := svc.GetByCode("LGA"); err == nil {
+		t.Error("expected a vetoed revert to change nothing")
+	}
+
+	if _, err := svc.RevertAirport("KLGA", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.GetByCode("LGA"); err != nil {
+		t.Errorf("expected the reverted airport back: %v", err)
+	}
+	if _, err := svc.RevertAirport("KLGA", nil); !errors.Is(err, ErrNoOverride) {
+		t.Errorf("expected ErrNoOverride, got %v", err)
+	}
+}
+
+func TestOverridesSurviveReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "airports.json")
+	if err := os.WriteFile(path, []byte(overrideTestData), 0644); err != nil {
+		t.Fatal(err)
+	}
+	svc, err := NewServiceFromPath(path, []byte(embeddedTestData))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svc.LoadOverrides([]Override{
+		{ICAO: "KJFK", Action: OverridePatch, Fields: json.RawMessage(`{"name": "Kennedy"}`)},
+		{ICAO: "EGLL", Action: OverridePatch, Fields: json.RawMessage(`{"name": "Heathrow"}`)}, // Not in the dataset
+	})
+	if apt, _ := svc.GetByCode("KJFK"); apt == nil || apt.Name != "Kennedy" {
+		t.Errorf("expected the loaded override, got %+v", apt)
+	}
+
+	if _, err := svc.Reload(TriggerAdmin); err != nil {
+		t.Fatal(err)
+	}
+	if apt, _ := svc.GetByCode("KJFK"); apt == nil || apt.Name != "Kennedy" {
+		t.Errorf("expected the override after reload, got %+v", apt)
+	}
+	if _, err := svc.GetByCode("EGLL"); err == nil {
+		t.Error("expected a patch without a dataset airport to be skipped")
+	}
+}
@@ -0,0 +1,104 @@
+package airports
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Page selects a window of results, either by offset or by the opaque cursor
+// returned with a previous page. A cursor records the sort key of the last
+// airport seen, so iteration neither skips nor repeats airports when the
+// dataset changes between requests. Cursor takes precedence over Offset.
+type Page struct {
+	Limit  int
+	Offset int
+	Cursor string
+}
+
+// PageInfo describes the window returned for a Page
+type PageInfo struct {
+	Total      int    // Matches across all pages
+	Offset     int    // Position of the first returned result
+	NextCursor string // Cursor for the following page (empty on the last page)
+}
+
+// HasNext reports whether results remain after a page of the given size
+func (p PageInfo) HasNext(limit int) bool {
+	return p.Offset+limit < p.Total
+}
+
+// encodeCursor makes a sort key opaque for use as a cursor
+func encodeCursor(key string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(key))
+}
+
+// decodeCursor returns the sort key stored in a cursor
+func decodeCursor(cursor string) (string, error) {
+	key, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(key) == 0 {
+		return "", ErrInvalidCursor
+	}
+	return string(key), nil
+}
+
+// paginate returns the window of items selected by page. Items must be sorted
+// ascending by key, and keys must be unique. With desc set, pages run from the
+// end of items backwards.
+func paginate[T any](items []T, page Page, key func(T) string, desc bool) ([]T, PageInfo, error) {
+	info := PageInfo{Total: len(items)}
+
+	if desc {
+		reversed := make([]T, len(items))
+		for i, item := range items {
+			reversed[len(items)-1-i] = item
+		}
+		items = reversed
+	}
+
+	start := page.Offset
+	if page.Cursor != "" {
+		after, err := decodeCursor(page.Cursor)
+		if err != nil {
+			return nil, info, err
+		}
+		start = sort.Search(len(items), func(i int) bool {
+			if desc {
+				return key(items[i]) < after
+			}
+			return key(items[i]) > after
+		})
+	}
+	if start < 0 {
+		start = 0
+	}
+	if start > len(items) {
+		start = len(items)
+	}
+	info.Offset = start
+
+	limit := max(page.Limit, 0)
+	end := min(start+limit, len(items))
+	if end < len(items) && end > start {
+		info.NextCursor = encodeCursor(key(items[end-1]))
+	}
+
+	return items[start:end], info, nil
+}
+
+// icaoKey orders airports by ICAO code
+func icaoKey(apt *Airport) string {
+	return apt.ICAO
+}
+
+// searchKey orders search results by descending score, then name and ICAO.
+// Scores have one decimal place and never exceed scoreCodeMatch, so the
+// inverted score sorts correctly as a fixed-width string.
+func searchKey(r ranked) string {
+	inverted := int(scoreCodeMatch*10) - int(r.score*10+0.5)
+	return fmt.Sprintf("%04d\x00%s\x00%s", inverted, r.airport.Name, r.airport.ICAO)
+}
@@ -0,0 +1,135 @@
+package airports
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSearchTotal(t *testing.T) {
+	svc := newSearchTestService()
+
+	results, total := svc.Search("london", 2, 0)
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	if total != 5 {
+		t.Errorf("total = %d, want 5", total)
+	}
+
+	results, total = svc.Search("london", 2, 4)
+	if len(results) != 1 || total != 5 {
+		t.Errorf("last page: got %d results, total %d; want 1, 5", len(results), total)
+	}
+}
+
+func TestListFilters(t *testing.T) {
+	svc := newSearchTestService()
+
+	list, info, err := svc.List(ListOptions{Filters: Filters{Country: "gb"}, Page: Page{Limit: 10}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Total != 4 || len(list) != 4 {
+		t.Fatalf("GB: got %d of %d, want 4 of 4", len(list), info.Total)
+	}
+	for i := 1; i < len(list); i++ {
+		if list[i-1].ICAO >= list[i].ICAO {
+			t.Errorf("results not ordered by ICAO: %s before %s", list[i-1].ICAO, list[i].ICAO)
+		}
+	}
+
+	_, info, _ = svc.List(ListOptions{Filters: Filters{Country: "CA", State: "ontario"}, Page: Page{Limit: 10}})
+	if info.Total != 1 {
+		t.Errorf("CA/Ontario total = %d, want 1", info.Total)
+	}
+}
+
+func TestCursorIteration(t *testing.T) {
+	svc := newSyntheticService(500)
+
+	seen := make(map[string]bool)
+	page := Page{Limit: 37}
+	for {
+		list, info, err := svc.List(ListOptions{Page: page})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, apt := range list {
+			if seen[apt.ICAO] {
+				t.Fatalf("%s returned twice", apt.ICAO)
+			}
+			seen[apt.ICAO] = true
+		}
+		if info.NextCursor == "" {
+			break
+		}
+		page.Cursor = info.NextCursor
+	}
+
+	if len(seen) != 500 {
+		t.Errorf("iterated %d airports, want 500", len(seen))
+	}
+}
+
+func TestCursorStableAcrossChanges(t *testing.T) {
+	data := syntheticDatabase(100)
+	svc := &Service{data: data, indexes: BuildIndexes(data)}
+
+	first, info, _ := svc.List(ListOptions{Page: Page{Limit: 10}})
+	lastSeen := first[len(first)-1].ICAO
+
+	// Remove an airport from the first page before fetching the second
+	delete(data, first[0].ICAO)
+	svc = &Service{data: data, indexes: BuildIndexes(data)}
+
+	second, _, err := svc.List(ListOptions{Page: Page{Limit: 10, Cursor: info.NextCursor}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second[0].ICAO <= lastSeen {
+		t.Errorf("second page starts at %s, want after %s", second[0].ICAO, lastSeen)
+	}
+
+	// The equivalent offset page would have skipped an airport
+	byOffset, _, _ := svc.List(ListOptions{Page: Page{Limit: 10, Offset: 10}})
+	if byOffset[0].ICAO == second[0].ICAO {
+		t.Errorf("expected offset and cursor pages to differ after a removal")
+	}
+}
+
+func TestSearchCursor(t *testing.T) {
+	svc := newSearchTestService()
+
+	all, _ := svc.Search("london", 100, 0)
+
+	var paged []SearchResult
+	page := Page{Limit: 2}
+	for {
+		results, info, err := svc.SearchPage(ListOptions{Query: "london", Page: page})
+		if err != nil {
+			t.Fatal(err)
+		}
+		paged = append(paged, results...)
+		if info.NextCursor == "" {
+			break
+		}
+		page.Cursor = info.NextCursor
+	}
+
+	if len(paged) != len(all) {
+		t.Fatalf("cursor pages returned %d results, want %d", len(paged), len(all))
+	}
+	for i := range all {
+		if paged[i].ICAO != all[i].ICAO {
+			t.Errorf("result %d = %s, want %s", i, paged[i].ICAO, all[i].ICAO)
+		}
+	}
+}
+
+func TestInvalidCursor(t *testing.T) {
+	svc := newSearchTestService()
+
+	if _, _, err := svc.List(ListOptions{Page: Page{Limit: 10, Cursor: "!!!"}}); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("err = %v, want ErrInvalidCursor", err)
+	}
+}
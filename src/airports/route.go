@@ -0,0 +1,122 @@
+package airports
+
+import "math"
+
+// Distance is a distance in kilometres, statute miles and nautical miles
+type Distance struct {
+	Km float64 `json:"km"`
+	Mi float64 `json:"mi"`
+	Nm float64 `json:"nm"`
+}
+
+// NewDistance expresses a distance in km in every unit
+func NewDistance(km float64) Distance {
+	return Distance{Km: km, Mi: km * KmToMiles, Nm: km * KmToNauticalMiles}
+}
+
+// RouteLeg is the great-circle path between two airports
+type RouteLeg struct {
+	From           *Airport
+	To             *Airport
+	Distance       Distance
+	InitialBearing float64       // Degrees true (0-360) on departure
+	FinalBearing   float64       // Degrees true (0-360) on arrival
+	Midpoint       Coordinates   // Halfway along the great circle
+	Waypoints      []Coordinates // From, interpolated points, To; nil unless requested
+}
+
+// Route is a sequence of great-circle legs through a list of airports
+type Route struct {
+	Legs     []RouteLeg
+	Distance Distance // Sum of the legs
+}
+
+// GreatCircle computes the great-circle leg between two airports. With
+// waypoints > 0 the leg also includes that many evenly spaced points
+// between the two airports.
+func GreatCircle(from, to *Airport, waypoints int) RouteLeg {
+	leg := RouteLeg{
+		From:     from,
+		To:       to,
+		Distance: NewDistance(haversine(from.Lat, from.Lon, to.Lat, to.Lon)),
+		Midpoint: Coordinates{Lat: from.Lat, Lon: from.Lon},
+	}
+
+	if leg.Distance.Km > 0 {
+		leg.InitialBearing = bearingDegrees(bearing(from.Lat, from.Lon, to.Lat, to.Lon))
+		// The final bearing is the reverse of the initial bearing from the far end
+		leg.FinalBearing = bearingDegrees(bearing(to.Lat, to.Lon, from.Lat, from.Lon) + math.Pi)
+		leg.Midpoint.Lat, leg.Midpoint.Lon = intermediatePoint(from.Lat, from.Lon, to.Lat, to.Lon, 0.5)
+	}
+
+	if waypoints > 0 {
+		leg.Waypoints = make([]Coordinates, 0, waypoints+2)
+		for i := 0; i <= waypoints+1; i++ {
+			lat, lon := intermediatePoint(from.Lat, from.Lon, to.Lat, to.Lon, float64(i)/float64(waypoints+1))
+			leg.Waypoints = append(leg.Waypoints, Coordinates{Lat: lat, Lon: lon})
+		}
+	}
+
+	return leg
+}
+
+// PlanRoute computes the legs between consecutive airports in stops
+func PlanRoute(stops []*Airport, waypoints int) Route {
+	route := Route{Legs: make([]RouteLeg, 0, len(stops))}
+
+	totalKm := 0.0
+	for i := 1; i < len(stops); i++ {
+		leg := GreatCircle(stops[i-1], stops[i], waypoints)
+		route.Legs = append(route.Legs, leg)
+		totalKm += leg.Distance.Km
+	}
+	route.Distance = NewDistance(totalKm)
+
+	return route
+}
+
+// bearingDegrees converts a bearing in radians to degrees in [0, 360)
+func bearingDegrees(rad float64) float64 {
+	deg := math.Mod(rad*180/math.Pi+360, 360)
+	if deg >= 360 {
+		deg -= 360
+	}
+	return deg
+}
+
+// DistanceMatrix computes the great-circle distance, in the unit system,
+// and the initial bearing in degrees from every airport to every other.
+// Rows and columns for nil airports are left nil, so indexes still match
+// stops.
+func DistanceMatrix(stops []*Airport, units string) (distances, bearings [][]*float64, unit string) {
+	_, unit = ConvertDistance(0, units)
+	distances = make([][]*float64, len(stops))
+	bearings = make([][]*float64, len(stops))
+
+	for i, from := range stops {
+		if from == nil {
+			continue
+		}
+		// One backing array per row keeps allocations down for large matrices
+		distanceRow := make([]float64, len(stops))
+		bearingRow := make([]float64, len(stops))
+		distances[i] = make([]*float64, len(stops))
+		bearings[i] = make([]*float64, len(stops))
+
+		for j, to := range stops {
+			if to == nil {
+				continue
+			}
+			if i != j {
+				distanceRow[j], _ = ConvertDistance(haversine(from.Lat, from.Lon, to.Lat, to.Lon), units)
+				if distanceRow[j] > 0 {
+					bearingRow[j] = bearingDegrees(bearing(from.Lat, from.Lon, to.Lat, to.Lon))
+				}
+			}
+			distances[i][j] = &distanceRow[j]
+			bearings[i][j] = &bearingRow[j]
+		}
+	}
+
+	return distances, bearings, unit
+}
@@ -0,0 +1,95 @@
+package airports
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGreatCircle(t *testing.T) {
+	jfk := &Airport{ICAO: "KJFK", Lat: 40.6398, Lon: -73.7789}
+	lhr := &Airport{ICAO: "EGLL", Lat: 51.4706, Lon: -0.4619}
+
+	leg := GreatCircle(jfk, lhr, 0)
+
+	checks := []struct {
+		name      string
+		got, want float64
+		tolerance float64
+	}{
+		{"km", leg.Distance.Km, 5540, 5},
+		{"mi", leg.Distance.Mi, 3442, 5},
+		{"nm", leg.Distance.Nm, 2991, 5},
+		{"initial bearing", leg.InitialBearing, 51.4, 0.5},
+		{"final bearing", leg.FinalBearing, 107.9, 0.5},
+		{"midpoint lat", leg.Midpoint.Lat, 52.2, 0.5},
+		{"midpoint lon", leg.Midpoint.Lon, -41.3, 0.5},
+	}
+	for _, c := range checks {
+		if math.Abs(c.got-c.want) > c.tolerance {
+			t.Errorf("%s = %.2f, want %.2f", c.name, c.got, c.want)
+		}
+	}
+	if leg.Waypoints != nil {
+		t.Errorf("expected no waypoints unless requested, got %d", len(leg.Waypoints))
+	}
+
+	leg = GreatCircle(jfk, lhr, 3)
+	if len(leg.Waypoints) != 5 {
+		t.Fatalf("expected 5 waypoints including both airports, got %d", len(leg.Waypoints))
+	}
+	first, last := leg.Waypoints[0], leg.Waypoints[4]
+	if math.Abs(first.Lat-jfk.Lat) > 1e-9 || math.Abs(last.Lon-lhr.Lon) > 1e-9 {
+		t.Errorf("waypoints should start at %s and end at %s, got %v and %v", jfk.ICAO, lhr.ICAO, first, last)
+	}
+}
+
+func TestPlanRoute(t *testing.T) {
+	jfk := &Airport{ICAO: "KJFK", Lat: 40.6398, Lon: -73.7789}
+	ord := &Airport{ICAO: "KORD", Lat: 41.9786, Lon: -87.9048}
+	lhr := &Airport{ICAO: "EGLL", Lat: 51.4706, Lon: -0.4619}
+
+	route := PlanRoute([]*Airport{jfk, ord, lhr}, 0)
+	if len(route.Legs) != 2 {
+		t.Fatalf("expected 2 legs, got %d", len(route.Legs))
+	}
+	if route.Legs[0].To != ord || route.Legs[1].From != ord {
+		t.Errorf("legs should meet at %s", ord.ICAO)
+	}
+
+	sum := route.Legs[0].Distance.Km + route.Legs[1].Distance.Km
+	if math.Abs(route.Distance.Km-sum) > 1e-9 {
+		t.Errorf("total distance %.1f km, want the sum of the legs %.1f km", route.Distance.Km, sum)
+	}
+
+	same := GreatCircle(jfk, jfk, 0)
+	if same.Distance.Km != 0 || same.InitialBearing != 0 || same.Midpoint.Lat != jfk.Lat {
+		t.Errorf("a zero-length leg should have no distance or bearing, got %+v", same)
+	}
+}
+
+func TestDistanceMatrix(t *testing.T) {
+	jfk := &Airport{ICAO: "KJFK", Lat: 40.6398, Lon: -73.7789}
+	lhr := &Airport{ICAO: "EGLL", Lat: 51.4706, Lon: -0.4619}
+
+	distances, bearings, unit := DistanceMatrix([]*Airport{jfk, nil, lhr}, UnitMetric)
+	if unit != "km" {
+		t.Errorf("unit = %q, want km", unit)
+	}
+	if distances[1] != nil || bearings[1] != nil {
+		t.Error("expected a nil row for the unresolved airport")
+	}
+	if distances[0][1] != nil || distances[2][1] != nil {
+		t.Error("expected nil cells in the unresolved airport's column")
+	}
+	if *distances[0][0] != 0 || *bearings[2][2] != 0 {
+		t.Error("expected zero distance and bearing on the diagonal")
+	}
+
+	leg := GreatCircle(jfk, lhr, 0)
+	if math.Abs(*distances[0][2]-leg.Distance.Km) > 1e-9 || math.Abs(*distances[2][0]-leg.Distance.Km) > 1e-9 {
+		t.Errorf("distances %.1f and %.1f, want %.1f both ways", *distances[0][2], *distances[2][0], leg.Distance.Km)
+	}
+	if math.Abs(*bearings[0][2]-leg.InitialBearing) > 1e-9 {
+		t.Errorf("bearing %.1f, want %.1f", *bearings[0][2], leg.InitialBearing)
+	}
+}
@@ -0,0 +1,264 @@
+package airports
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Search relevance scores. Code matches always outrank text matches.
+const (
+	scoreCodeMatch   = 100.0 // Exact ICAO or IATA code
+	scoreTextMax     = 70.0  // All query tokens matched exactly
+	scoreCityBonus   = 15.0  // Query is the whole city name
+	scoreNameBonus   = 8.0   // Name starts with the query
+	scoreIATABonus   = 12.0  // Airport has an IATA code (scheduled service)
+	scoreTextCeiling = 99.0
+
+	tokenExact  = 10.0
+	tokenPrefix = 6.0
+	tokenFuzzy  = 4.0 // Minus the edit distance
+)
+
+// SearchResult is an airport matched by Search with its relevance score
+type SearchResult struct {
+	Airport
+	Score float64 `json:"score"`
+}
+
+// searchIndex is an inverted index over airport name, city and code tokens
+type searchIndex struct {
+	postings map[string][]*Airport // token -> airports containing it
+	tokens   []string              // sorted distinct tokens for prefix lookup
+	byLength map[int][]string      // distinct tokens by rune length for fuzzy lookup
+}
+
+// newSearchIndex creates an empty inverted index
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		postings: make(map[string][]*Airport),
+		byLength: make(map[int][]string),
+	}
+}
+
+// insert adds an airport's searchable tokens to the index
+func (idx *searchIndex) insert(apt *Airport) {
+	seen := make(map[string]bool)
+	add := func(token string) {
+		if token == "" || seen[token] {
+			return
+		}
+		seen[token] = true
+		idx.postings[token] = append(idx.postings[token], apt)
+	}
+
+	for _, token := range tokenize(apt.Name) {
+		add(token)
+	}
+	for _, token := range tokenize(apt.City) {
+		add(token)
+	}
+	add(normalizeText(apt.ICAO))
+	add(normalizeText(apt.IATA))
+}
+
+// finalize builds the token lists used for prefix and fuzzy matching
+func (idx *searchIndex) finalize() {
+	idx.tokens = make([]string, 0, len(idx.postings))
+	for token := range idx.postings {
+		idx.tokens = append(idx.tokens, token)
+	}
+	sort.Strings(idx.tokens)
+
+	for _, token := range idx.tokens {
+		n := len([]rune(token))
+		idx.byLength[n] = append(idx.byLength[n], token)
+	}
+}
+
+// match returns every airport matching a single query token with the best
+// score for that token (exact, then prefix, then fuzzy)
+func (idx *searchIndex) match(queryToken string) map[*Airport]float64 {
+	matches := make(map[*Airport]float64)
+	record := func(token string, score float64) {
+		for _, apt := range idx.postings[token] {
+			if score > matches[apt] {
+				matches[apt] = score
+			}
+		}
+	}
+
+	record(queryToken, tokenExact)
+
+	// Prefix matches from the sorted token list
+	start := sort.SearchStrings(idx.tokens, queryToken)
+	for i := start; i < len(idx.tokens) && strings.HasPrefix(idx.tokens[i], queryToken); i++ {
+		if idx.tokens[i] != queryToken {
+			record(idx.tokens[i], tokenPrefix)
+		}
+	}
+
+	// Fuzzy matches among tokens of similar length
+	maxDist := maxEditDistance(queryToken)
+	if maxDist > 0 {
+		n := len([]rune(queryToken))
+		for length := n - maxDist; length <= n+maxDist; length++ {
+			for _, token := range idx.byLength[length] {
+				if d := levenshtein(queryToken, token, maxDist); d > 0 && d <= maxDist {
+					record(token, tokenFuzzy-float64(d))
+				}
+			}
+		}
+	}
+
+	return matches
+}
+
+// Search finds airports matching the query, ranked by relevance: exact codes
+// first, then exact, prefix and fuzzy (typo-tolerant) word matches. It returns
+// one page of results and the total number of matches.
+func (s *Service) Search(query string, limit, offset int) ([]SearchResult, int) {
+	results, info, _ := s.SearchPage(ListOptions{
+		Query: query,
+		Page:  Page{Limit: limit, Offset: offset},
+	})
+	return results, info.Total
+}
+
+// rankedSearch scores and sorts every airport matching the query
+func (s *Service) rankedSearch(query string) []ranked {
+	normalized := normalizeText(strings.TrimSpace(query))
+	queryTokens := tokenize(query)
+	if len(queryTokens) == 0 {
+		return nil
+	}
+
+	scores := make(map[*Airport]float64)
+
+	// Text matches: every query token must match the airport
+	for i, token := range queryTokens {
+		matches := s.indexes.search.match(token)
+		if i == 0 {
+			for apt, score := range matches {
+				scores[apt] = score
+			}
+			continue
+		}
+		for apt, score := range scores {
+			if tokenScore, ok := matches[apt]; ok {
+				scores[apt] = score + tokenScore
+			} else {
+				delete(scores, apt)
+			}
+		}
+	}
+
+	maxTokenScore := tokenExact * float64(len(queryTokens))
+	for apt, tokenScore := range scores {
+		score := scoreTextMax * tokenScore / maxTokenScore
+		if normalizeText(apt.City) == normalized {
+			score += scoreCityBonus
+		}
+		if strings.HasPrefix(normalizeText(apt.Name), normalized) {
+			score += scoreNameBonus
+		}
+		if apt.IATA != "" {
+			score += scoreIATABonus
+		}
+		scores[apt] = min(score, scoreTextCeiling)
+	}
+
+	// Exact code matches
+	code := strings.ToUpper(strings.TrimSpace(query))
+	if apt, ok := s.indexes.ByICAO[code]; ok {
+		scores[apt] = scoreCodeMatch
+	}
+	for _, apt := range s.indexes.ByIATA[code] {
+		scores[apt] = scoreCodeMatch
+	}
+
+	results := make([]ranked, 0, len(scores))
+	for apt, score := range scores {
+		results = append(results, ranked{airport: apt, score: roundScore(score)})
+	}
+
+	// Sort by score, then name
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].score != results[j].score {
+			return results[i].score > results[j].score
+		}
+		if results[i].airport.Name != results[j].airport.Name {
+			return results[i].airport.Name < results[j].airport.Name
+		}
+		return results[i].airport.ICAO < results[j].airport.ICAO
+	})
+
+	return results
+}
+
+// tokenize splits text into normalized words
+func tokenize(s string) []string {
+	return strings.FieldsFunc(normalizeText(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// maxEditDistance returns how many typos to tolerate for a query token
+func maxEditDistance(token string) int {
+	switch n := len([]rune(token)); {
+	case n >= 8:
+		return 2
+	case n >= 4:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// levenshtein returns the edit distance between a and b, or limit+1 once the
+// distance is known to exceed limit
+func levenshtein(a, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	if abs(len(ra)-len(rb)) > limit {
+		return limit + 1
+	}
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if curr[j] < rowMin {
+				rowMin = curr[j]
+			}
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// roundScore rounds a score to one decimal place
+func roundScore(score float64) float64 {
+	return float64(int(score*10+0.5)) / 10
+}
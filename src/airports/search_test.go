@@ -0,0 +1,100 @@
+package airports
+
+import "testing"
+
+func newSearchTestService() *Service {
+	data := AirportDatabase{
+		"EGLL": {ICAO: "EGLL", IATA: "LHR", Name: "London Heathrow Airport", City: "London", Country: "GB"},
+		"EGKK": {ICAO: "EGKK", IATA: "LGW", Name: "London Gatwick Airport", City: "London", Country: "GB"},
+		"EGLC": {ICAO: "EGLC", IATA: "LCY", Name: "London City Airport", City: "London", Country: "GB"},
+		"CYXU": {ICAO: "CYXU", IATA: "YXU", Name: "London Airport", City: "London", State: "Ontario", Country: "CA"},
+		"EGLW": {ICAO: "EGLW", Name: "London Heliport", City: "London", Country: "GB"},
+		"KJFK": {ICAO: "KJFK", IATA: "JFK", Name: "John F Kennedy International Airport", City: "New York", Country: "US"},
+		"KLGA": {ICAO: "KLGA", IATA: "LGA", Name: "La Guardia Airport", City: "New York", Country: "US"},
+		"KLHR": {ICAO: "KLHR", Name: "Heathrow Field", City: "Lamar", Country: "US"},
+		"SBGR": {ICAO: "SBGR", IATA: "GRU", Name: "Guarulhos International Airport", City: "Sao Paulo", Country: "BR"},
+	}
+	return &Service{data: data, indexes: BuildIndexes(data)}
+}
+
+func TestSearchRanking(t *testing.T) {
+	svc := newSearchTestService()
+
+	tests := []struct {
+		query    string
+		wantTop  string
+		minCount int
+	}{
+		{"LHR", "EGLL", 1},             // Exact IATA code
+		{"egll", "EGLL", 1},            // Exact ICAO code, any case
+		{"heathrow", "EGLL", 2},        // Exact word
+		{"Heathro", "EGLL", 2},         // Prefix while typing
+		{"Heatrhow", "EGLL", 1},        // Transposition typo
+		{"london heathrow", "EGLL", 1}, // All words must match
+		{"new york", "KJFK", 2},        // City match
+		{"Guarulos", "SBGR", 1},        // Missing letter
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			results, _ := svc.Search(tt.query, 10, 0)
+			if len(results) < tt.minCount {
+				t.Fatalf("Expected at least %d results, got %d", tt.minCount, len(results))
+			}
+			if results[0].ICAO != tt.wantTop {
+				t.Errorf("Expected %s first, got %s (score %.1f)", tt.wantTop, results[0].ICAO, results[0].Score)
+			}
+			for i := 1; i < len(results); i++ {
+				if results[i].Score > results[i-1].Score {
+					t.Errorf("Results not sorted by score at %d", i)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchCodeOutranksText(t *testing.T) {
+	svc := newSearchTestService()
+
+	results, _ := svc.Search("LHR", 10, 0)
+	if results[0].Score != scoreCodeMatch {
+		t.Errorf("Expected code match score %.0f, got %.1f", scoreCodeMatch, results[0].Score)
+	}
+
+	// Airports with scheduled service rank above heliports for the same words
+	results, _ = svc.Search("london", 10, 0)
+	if last := results[len(results)-1]; last.ICAO != "EGLW" {
+		t.Errorf("Expected heliport EGLW last, got %s", last.ICAO)
+	}
+}
+
+func TestSearchNoMatch(t *testing.T) {
+	svc := newSearchTestService()
+
+	if results, _ := svc.Search("zzzzqqq", 10, 0); len(results) != 0 {
+		t.Errorf("Expected no results, got %d", len(results))
+	}
+	if results, _ := svc.Search("  ", 10, 0); len(results) != 0 {
+		t.Errorf("Expected no results for blank query, got %d", len(results))
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		limit int
+		want  int
+	}{
+		{"heathrow", "heathrow", 2, 0},
+		{"heatrow", "heathrow", 2, 1},
+		{"heahtrow", "heathrow", 2, 2},
+		{"kennedy", "heathrow", 2, 3},
+		{"zürich", "zurich", 1, 1},
+	}
+
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b, tt.limit); got != tt.want {
+			t.Errorf("levenshtein(%q, %q, %d) = %d, want %d", tt.a, tt.b, tt.limit, got, tt.want)
+		}
+	}
+}
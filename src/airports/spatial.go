@@ -0,0 +1,204 @@
+package airports
+
+import (
+	"math"
+	"sort"
+)
+
+// Spatial grid dimensions (1 degree cells)
+const (
+	gridCellDegrees = 1.0
+	gridRows        = int(180 / gridCellDegrees)
+	gridCols        = int(360 / gridCellDegrees)
+)
+
+// Earth geometry constants
+const (
+	earthRadiusKm     = 6371.0
+	kmPerDegreeLat    = earthRadiusKm * math.Pi / 180
+	halfCircumference = earthRadiusKm * math.Pi // Farthest possible distance between two points
+)
+
+// airportDistance pairs an airport with its distance (km) from a query point
+type airportDistance struct {
+	airport  *Airport
+	distance float64
+}
+
+// spatialIndex buckets airports into a fixed lat/lon grid so radius,
+// k-nearest and bounding box queries only visit nearby cells
+type spatialIndex struct {
+	cells [][]*Airport // row-major, gridRows x gridCols
+	size  int
+}
+
+// newSpatialIndex creates an empty grid
+func newSpatialIndex() *spatialIndex {
+	return &spatialIndex{
+		cells: make([][]*Airport, gridRows*gridCols),
+	}
+}
+
+// insert adds an airport to the grid
+func (g *spatialIndex) insert(apt *Airport) {
+	idx := gridRow(apt.Lat)*gridCols + gridCol(apt.Lon)
+	g.cells[idx] = append(g.cells[idx], apt)
+	g.size++
+}
+
+// sortCells orders each cell by ICAO so query results are deterministic
+func (g *spatialIndex) sortCells() {
+	for _, cell := range g.cells {
+		if len(cell) > 1 {
+			sort.Slice(cell, func(i, j int) bool {
+				return cell[i].ICAO < cell[j].ICAO
+			})
+		}
+	}
+}
+
+// searchBox calls fn for every airport inside the bounds (minLon <= maxLon)
+func (g *spatialIndex) searchBox(minLat, maxLat, minLon, maxLon float64, fn func(*Airport)) {
+	if minLat > maxLat || minLon > maxLon {
+		return
+	}
+
+	rowStart, rowEnd := gridRow(minLat), gridRow(maxLat)
+	colStart, colEnd := gridCol(math.Max(minLon, -180)), gridCol(math.Min(maxLon, 180))
+
+	for row := rowStart; row <= rowEnd; row++ {
+		for col := colStart; col <= colEnd; col++ {
+			for _, apt := range g.cells[row*gridCols+col] {
+				if apt.Lat >= minLat && apt.Lat <= maxLat &&
+					apt.Lon >= minLon && apt.Lon <= maxLon {
+					fn(apt)
+				}
+			}
+		}
+	}
+}
+
+// searchBoxWrapped is searchBox for boxes that may cross the antimeridian.
+// A box with minLon > maxLon runs east from minLon across 180 to maxLon.
+func (g *spatialIndex) searchBoxWrapped(minLat, maxLat, minLon, maxLon float64, fn func(*Airport)) {
+	if minLon <= maxLon {
+		g.searchBox(minLat, maxLat, minLon, maxLon, fn)
+		return
+	}
+	g.searchBox(minLat, maxLat, minLon, 180, fn)
+	g.searchBox(minLat, maxLat, -180, maxLon, fn)
+}
+
+// withinRadius returns all airports within radiusKm of the point (unsorted).
+// A nil match accepts every airport.
+func (g *spatialIndex) withinRadius(lat, lon, radiusKm float64, match func(*Airport) bool) []airportDistance {
+	results := []airportDistance{}
+	if radiusKm < 0 {
+		return results
+	}
+
+	collect := func(apt *Airport) {
+		if match != nil && !match(apt) {
+			return
+		}
+		if dist := haversine(lat, lon, apt.Lat, apt.Lon); dist <= radiusKm {
+			results = append(results, airportDistance{apt, dist})
+		}
+	}
+
+	// Latitude band covered by the circle
+	latDelta := radiusKm / kmPerDegreeLat
+	minLat, maxLat := lat-latDelta, lat+latDelta
+
+	// If the circle reaches a pole, or covers a quarter of the globe, every
+	// longitude is in range
+	angular := radiusKm / earthRadiusKm
+	if minLat <= -90 || maxLat >= 90 || angular >= math.Pi/2 {
+		g.searchBox(math.Max(minLat, -90), math.Min(maxLat, 90), -180, 180, collect)
+		return results
+	}
+
+	// Widest longitude extent of a spherical cap at this latitude
+	sinRatio := math.Sin(angular) / math.Cos(lat*math.Pi/180)
+	if sinRatio >= 1 {
+		g.searchBox(minLat, maxLat, -180, 180, collect)
+		return results
+	}
+	lonDelta := math.Asin(sinRatio) * 180 / math.Pi
+
+	minLon, maxLon := lon-lonDelta, lon+lonDelta
+	switch {
+	case maxLon-minLon >= 360:
+		g.searchBox(minLat, maxLat, -180, 180, collect)
+	case minLon < -180:
+		// Wraps west across the antimeridian
+		g.searchBox(minLat, maxLat, -180, maxLon, collect)
+		g.searchBox(minLat, maxLat, minLon+360, 180, collect)
+	case maxLon > 180:
+		// Wraps east across the antimeridian
+		g.searchBox(minLat, maxLat, minLon, 180, collect)
+		g.searchBox(minLat, maxLat, -180, maxLon-360, collect)
+	default:
+		g.searchBox(minLat, maxLat, minLon, maxLon, collect)
+	}
+
+	return results
+}
+
+// nearest returns the k matching airports closest to the point, sorted by
+// distance. The search radius grows until k airports are found or the whole
+// globe is covered, so there is no distance cap.
+func (g *spatialIndex) nearest(lat, lon float64, k int, match func(*Airport) bool) []airportDistance {
+	if k <= 0 || g.size == 0 {
+		return []airportDistance{}
+	}
+
+	radius := 50.0
+	for {
+		results := g.withinRadius(lat, lon, radius, match)
+		if len(results) >= k || radius >= halfCircumference {
+			sortByDistance(results)
+			if len(results) > k {
+				results = results[:k]
+			}
+			return results
+		}
+		radius *= 4
+	}
+}
+
+// sortByDistance orders results closest first, breaking ties by ICAO
+func sortByDistance(results []airportDistance) {
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].distance != results[j].distance {
+			return results[i].distance < results[j].distance
+		}
+		return results[i].airport.ICAO < results[j].airport.ICAO
+	})
+}
+
+// gridRow returns the grid row for a latitude
+func gridRow(lat float64) int {
+	row := int(math.Floor((lat + 90) / gridCellDegrees))
+	if row < 0 {
+		return 0
+	}
+	if row >= gridRows {
+		return gridRows - 1
+	}
+	return row
+}
+
+// gridCol returns the grid column for a longitude. Exactly 180 shares the
+// last column; anything further out wraps around.
+func gridCol(lon float64) int {
+	if lon == 180 {
+		return gridCols - 1
+	}
+	col := int(math.Floor((lon + 180) / gridCellDegrees))
+	col %= gridCols
+	if col < 0 {
+		col += gridCols
+	}
+	return col
+}
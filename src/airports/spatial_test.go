@@ -0,0 +1,265 @@
+package airports
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// syntheticDatabase generates n airports with a realistic mix of dense
+// clusters and sparse worldwide coverage, including points near the poles
+// and the antimeridian
+func syntheticDatabase(n int) AirportDatabase {
+	rng := rand.New(rand.NewSource(42))
+	clusters := [][2]float64{
+		{40, -95}, {50, 10}, {35, 135}, {-25, 135}, {-15, -50}, {60, -150}, {-18, 178},
+	}
+
+	data := make(AirportDatabase, n)
+	for i := 0; i < n; i++ {
+		var lat, lon float64
+		if i%5 < 3 {
+			c := clusters[rng.Intn(len(clusters))]
+			lat = c[0] + rng.NormFloat64()*6
+			lon = c[1] + rng.NormFloat64()*12
+		} else {
+			// Uniform over the sphere
+			lat = math.Asin(2*rng.Float64()-1) * 180 / math.Pi
+			lon = rng.Float64()*360 - 180
+		}
+		lat = math.Max(-90, math.Min(90, lat))
+		if lon >= 180 {
+			lon -= 360
+		} else if lon < -180 {
+			lon += 360
+		}
+
+		icao := fmt.Sprintf("X%05d", i)
+		data[icao] = Airport{ICAO: icao, Name: "Synthetic " + icao, Lat: lat, Lon: lon}
+	}
+	return data
+}
+
+func newSyntheticService(n int) *Service {
+	data := syntheticDatabase(n)
+	return &Service{data: data, indexes: BuildIndexes(data)}
+}
+
+// bruteForceNearby is the reference linear scan the spatial index replaces
+func bruteForceNearby(data AirportDatabase, lat, lon, radiusKm float64) []string {
+	type result struct {
+		icao     string
+		distance float64
+	}
+	results := []result{}
+	for _, apt := range data {
+		if dist := haversine(lat, lon, apt.Lat, apt.Lon); dist <= radiusKm {
+			results = append(results, result{apt.ICAO, dist})
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].distance != results[j].distance {
+			return results[i].distance < results[j].distance
+		}
+		return results[i].icao < results[j].icao
+	})
+
+	icaos := make([]string, len(results))
+	for i, r := range results {
+		icaos[i] = r.icao
+	}
+	return icaos
+}
+
+// bruteForceBBox is the reference linear scan for bounding boxes
+func bruteForceBBox(data AirportDatabase, minLat, maxLat, minLon, maxLon float64) []string {
+	icaos := []string{}
+	for _, apt := range data {
+		if apt.Lat >= minLat && apt.Lat <= maxLat && apt.Lon >= minLon && apt.Lon <= maxLon {
+			icaos = append(icaos, apt.ICAO)
+		}
+	}
+	sort.Strings(icaos)
+	return icaos
+}
+
+func icaosOf(airports []*Airport) []string {
+	icaos := make([]string, len(airports))
+	for i, apt := range airports {
+		icaos[i] = apt.ICAO
+	}
+	return icaos
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSpatialIndexMatchesLinearScan(t *testing.T) {
+	svc := newSyntheticService(5000)
+
+	tests := []struct {
+		name     string
+		lat, lon float64
+		radius   float64
+	}{
+		{"dense cluster", 40, -95, 150},
+		{"antimeridian east", -18, 179.5, 400},
+		{"antimeridian west", 52, -179.8, 800},
+		{"north pole", 89.5, 30, 300},
+		{"south pole", -89, -120, 500},
+		{"high latitude", 78, 15, 600},
+		{"empty ocean", -45, -130, 50},
+		{"quarter globe", 0, 0, 10500},
+		{"whole globe", 10, 10, 20100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := icaosOf(svc.GetNearby(tt.lat, tt.lon, tt.radius, math.MaxInt32))
+			want := bruteForceNearby(svc.data, tt.lat, tt.lon, tt.radius)
+			if !equalStrings(got, want) {
+				t.Errorf("GetNearby returned %d airports, linear scan found %d", len(got), len(want))
+			}
+
+			withDist := svc.GetNearbyWithDistance(tt.lat, tt.lon, tt.radius, 10, UnitMetric)
+			for i := 1; i < len(withDist); i++ {
+				if withDist[i].Distance < withDist[i-1].Distance {
+					t.Fatalf("results not sorted by distance at %d", i)
+				}
+			}
+		})
+	}
+}
+
+func TestSpatialIndexBoundingBox(t *testing.T) {
+	svc := newSyntheticService(5000)
+
+	boxes := [][4]float64{
+		{30, 50, -110, -80},
+		{-90, 90, -180, 180},
+		{-20, -10, 170, 180},
+		{85, 90, -180, -170},
+		{10, 10.5, 20, 20.5},
+	}
+
+	for _, b := range boxes {
+		got := icaosOf(svc.GetInBoundingBox(b[0], b[1], b[2], b[3]))
+		sort.Strings(got)
+		want := bruteForceBBox(svc.data, b[0], b[1], b[2], b[3])
+		if !equalStrings(got, want) {
+			t.Errorf("GetInBoundingBox(%v) returned %d airports, linear scan found %d", b, len(got), len(want))
+		}
+	}
+}
+
+func TestSpatialIndexBoundingBoxAntimeridian(t *testing.T) {
+	svc := newSyntheticService(5000)
+
+	// minLon > maxLon runs east across the antimeridian
+	got := icaosOf(svc.GetInBoundingBox(-30, 0, 170, -170))
+	sort.Strings(got)
+	want := append(bruteForceBBox(svc.data, -30, 0, 170, 180), bruteForceBBox(svc.data, -30, 0, -180, -170)...)
+	sort.Strings(want)
+
+	if len(want) == 0 {
+		t.Fatal("expected synthetic airports near the antimeridian")
+	}
+	if !equalStrings(got, want) {
+		t.Errorf("GetInBoundingBox across the antimeridian returned %d airports, linear scan found %d", len(got), len(want))
+	}
+}
+
+func TestSpatialIndexNearest(t *testing.T) {
+	svc := newSyntheticService(2000)
+
+	for _, p := range [][2]float64{{0, -160}, {-75, 100}, {40, -95}, {-18, -179.9}} {
+		got := svc.indexes.spatial.nearest(p[0], p[1], 5, nil)
+		want := bruteForceNearby(svc.data, p[0], p[1], halfCircumference+1)[:5]
+
+		gotICAOs := make([]string, len(got))
+		for i, r := range got {
+			gotICAOs[i] = r.airport.ICAO
+		}
+		if !equalStrings(gotICAOs, want) {
+			t.Errorf("nearest(%v) = %v, want %v", p, gotICAOs, want)
+		}
+	}
+}
+
+func BenchmarkNearbyIndexed(b *testing.B) {
+	svc := newSyntheticService(29000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		svc.GetNearbyWithDistance(40.6398, -73.7789, 50, 20, UnitImperial)
+	}
+}
+
+func BenchmarkNearbyLinearScan(b *testing.B) {
+	svc := newSyntheticService(29000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bruteForceNearby(svc.data, 40.6398, -73.7789, 50)
+	}
+}
+
+// The dense benchmarks search 500 km around the centre of the North American
+// cluster, a few hundred airports, and return all of them: the worst case for
+// the index compared with the linear scan
+
+func BenchmarkNearbyIndexedDense(b *testing.B) {
+	svc := newSyntheticService(29000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		svc.GetNearbyWithDistance(40, -95, 500, 1000, UnitMetric)
+	}
+}
+
+func BenchmarkNearbyLinearScanDense(b *testing.B) {
+	svc := newSyntheticService(29000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bruteForceNearby(svc.data, 40, -95, 500)
+	}
+}
+
+func BenchmarkBoundingBoxIndexed(b *testing.B) {
+	svc := newSyntheticService(29000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		svc.GetInBoundingBox(40, 41, -74, -73)
+	}
+}
+
+func BenchmarkBoundingBoxLinearScan(b *testing.B) {
+	svc := newSyntheticService(29000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bruteForceBBox(svc.data, 40, 41, -74, -73)
+	}
+}
+
+func BenchmarkBuildIndexes(b *testing.B) {
+	data := syntheticDatabase(29000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		BuildIndexes(data)
+	}
+}
@@ -0,0 +1,118 @@
+package airports
+
+import "strings"
+
+// Airport types, as used by OurAirports
+const (
+	TypeLargeAirport  = "large_airport"
+	TypeMediumAirport = "medium_airport"
+	TypeSmallAirport  = "small_airport"
+	TypeHeliport      = "heliport"
+	TypeSeaplaneBase  = "seaplane_base"
+	TypeBalloonport   = "balloonport"
+	TypeClosed        = "closed"
+)
+
+// AirportTypes lists every airport type, largest first
+var AirportTypes = []string{
+	TypeLargeAirport,
+	TypeMediumAirport,
+	TypeSmallAirport,
+	TypeSeaplaneBase,
+	TypeHeliport,
+	TypeBalloonport,
+	TypeClosed,
+}
+
+// typeAliases maps accepted type parameter values to airport types
+var typeAliases = map[string]string{
+	"large":    TypeLargeAirport,
+	"medium":   TypeMediumAirport,
+	"small":    TypeSmallAirport,
+	"seaplane": TypeSeaplaneBase,
+	"balloon":  TypeBalloonport,
+}
+
+// typeRanks orders airport types by importance. Airports without a type
+// rank as small airports, so datasets without types keep their order.
+var typeRanks = map[string]int{
+	TypeLargeAirport:  5,
+	TypeMediumAirport: 4,
+	TypeSmallAirport:  3,
+	"":                3,
+	TypeSeaplaneBase:  2,
+	TypeHeliport:      1,
+	TypeBalloonport:   1,
+	TypeClosed:        0,
+}
+
+// ParseAirportType resolves an airport type or its short alias, such as
+// "large" for large_airport
+func ParseAirportType(s string) (string, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if t, ok := typeAliases[s]; ok {
+		return t, true
+	}
+	if _, ok := typeRanks[s]; ok && s != "" {
+		return s, true
+	}
+	return "", false
+}
+
+// Runway is one runway of an airport
+type Runway struct {
+	Ident    string  `json:"ident"`               // Both ends, e.g. "04L/22R"
+	LengthFt int     `json:"length_ft,omitempty"` // In feet (source data)
+	WidthFt  int     `json:"width_ft,omitempty"`  // In feet (source data)
+	Surface  string  `json:"surface,omitempty"`   // Source surface code, e.g. ASP, CON, TURF
+	Heading  float64 `json:"heading,omitempty"`   // Degrees true at the low end
+	Lighted  bool    `json:"lighted"`
+	Closed   bool    `json:"closed"`
+}
+
+// Frequency is one radio frequency of an airport
+type Frequency struct {
+	Type        string  `json:"type"` // e.g. TWR, GND, ATIS
+	Description string  `json:"description,omitempty"`
+	MHz         float64 `json:"mhz"`
+}
+
+// Importance ranks an airport for display: larger types first, then
+// airports with an IATA code
+func (a *Airport) Importance() int {
+	rank, ok := typeRanks[a.Type]
+	if !ok {
+		rank = typeRanks[""]
+	}
+	rank *= 2
+	if a.IATA != "" {
+		rank++
+	}
+	return rank
+}
+
+// LongestRunway returns the length in feet of the longest open runway, or
+// 0 when no runway length is known
+func (a *Airport) LongestRunway() int {
+	longest := 0
+	for _, rwy := range a.Runways {
+		if !rwy.Closed && rwy.LengthFt > longest {
+			longest = rwy.LengthFt
+		}
+	}
+	return longest
+}
+
+// hasRunway reports whether an open runway is at least minLength feet long
+// and, when surface is set, has that surface
+func (a *Airport) hasRunway(minLength int, surface string) bool {
+	for _, rwy := range a.Runways {
+		if rwy.Closed || rwy.LengthFt < minLength {
+			continue
+		}
+		if surface == "" || strings.EqualFold(rwy.Surface, surface) {
+			return true
+		}
+	}
+	return false
+}
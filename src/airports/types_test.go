@@ -0,0 +1,59 @@
+package airports
+
+import "testing"
+
+func TestParseAirportType(t *testing.T) {
+	tests := map[string]string{
+		"large":          TypeLargeAirport,
+		"Medium_Airport": TypeMediumAirport,
+		"seaplane":       TypeSeaplaneBase,
+		" heliport ":     TypeHeliport,
+		"closed":         TypeClosed,
+	}
+	for input, want := range tests {
+		if got, ok := ParseAirportType(input); !ok || got != want {
+			t.Errorf("ParseAirportType(%q) = %q, %v, want %q", input, got, ok, want)
+		}
+	}
+	for _, input := range []string{"", "spaceport"} {
+		if _, ok := ParseAirportType(input); ok {
+			t.Errorf("expected %q to be rejected", input)
+		}
+	}
+}
+
+func TestAirportImportance(t *testing.T) {
+	untyped := &Airport{ICAO: "AAAA"}
+	untypedIATA := &Airport{ICAO: "BBBB", IATA: "BBB"}
+	large := &Airport{ICAO: "CCCC", Type: TypeLargeAirport}
+	heliport := &Airport{ICAO: "DDDD", IATA: "DDD", Type: TypeHeliport}
+
+	if untypedIATA.Importance() <= untyped.Importance() {
+		t.Error("expected an IATA code to raise importance")
+	}
+	if large.Importance() <= untypedIATA.Importance() {
+		t.Error("expected a large airport to outrank an untyped one")
+	}
+	if heliport.Importance() >= untyped.Importance() {
+		t.Error("expected a heliport to rank below an untyped airport")
+	}
+}
+
+func TestGetByCodeLocalCodes(t *testing.T) {
+	data := AirportDatabase{
+		"KJFK": {ICAO: "KJFK", IATA: "JFK", FAA: "JFK", LocalCode: "JFK"},
+		"K00A": {ICAO: "K00A", FAA: "00A", LocalCode: "00A"},
+		"CYTZ": {ICAO: "CYTZ", IATA: "YTZ", LocalCode: "CYTZ"},
+	}
+	svc := &Service{data: data, indexes: BuildIndexes(data)}
+
+	if apt, err := svc.GetByCode("00a"); err != nil || apt.ICAO != "K00A" {
+		t.Errorf("expected the FAA identifier to find K00A, got %v %v", apt, err)
+	}
+	if got := len(svc.indexes.ByLocal["JFK"]); got != 1 {
+		t.Errorf("expected matching FAA and local codes to be indexed once, got %d", got)
+	}
+	if got := len(svc.GetByType(TypeLargeAirport)); got != 0 {
+		t.Errorf("expected no typed airports, got %d", got)
+	}
+}
@@ -0,0 +1,62 @@
+package airports
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseUnits(t *testing.T) {
+	tests := map[string]string{
+		"":         UnitImperial,
+		"imperial": UnitImperial,
+		"mi":       UnitImperial,
+		"metric":   UnitMetric,
+		"KM":       UnitMetric,
+		"nautical": UnitNautical,
+		"aviation": UnitNautical,
+		"nm":       UnitNautical,
+		" NMI ":    UnitNautical,
+		"furlongs": UnitImperial,
+	}
+	for param, want := range tests {
+		if got := ParseUnits(param); got != want {
+			t.Errorf("ParseUnits(%q) = %q, want %q", param, got, want)
+		}
+	}
+
+	if ValidUnits("furlongs") || ValidUnits("") {
+		t.Error("expected unknown and empty units to be invalid")
+	}
+	if !ValidUnits("nautical") || !ValidUnits("Metric") {
+		t.Error("expected nautical and metric to be valid")
+	}
+}
+
+func TestConvertUnits(t *testing.T) {
+	tests := []struct {
+		units        string
+		distance     float64
+		distanceUnit string
+		elevation    float64
+		elevUnit     string
+	}{
+		{UnitImperial, 62.1371, "mi", 1000, "ft"},
+		{UnitMetric, 100, "km", 304.8, "m"},
+		{UnitNautical, 53.9957, "nm", 1000, "ft"},
+	}
+
+	for _, tt := range tests {
+		distance, unit := ConvertDistance(100, tt.units)
+		if math.Abs(distance-tt.distance) > 0.001 || unit != tt.distanceUnit {
+			t.Errorf("%s: 100 km = %.4f %s, want %.4f %s", tt.units, distance, unit, tt.distance, tt.distanceUnit)
+		}
+		if km := DistanceToKm(distance, tt.units); math.Abs(km-100) > 1e-9 {
+			t.Errorf("%s: %.4f %s converts back to %.6f km, want 100", tt.units, distance, unit, km)
+		}
+
+		elevation, unit := ConvertElevation(1000, tt.units)
+		if math.Abs(elevation-tt.elevation) > 0.001 || unit != tt.elevUnit {
+			t.Errorf("%s: 1000 ft = %.1f %s, want %.1f %s", tt.units, elevation, unit, tt.elevation, tt.elevUnit)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package airports
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+)
+
+// retainedVersions is how many dataset versions are kept for Changes
+const retainedVersions = 10
+
+// ErrUnknownVersion is returned for a dataset version that is not retained
+var ErrUnknownVersion = errors.New("dataset version not retained")
+
+// DatasetVersion identifies the content of a served dataset. Datasets with
+// the same airports have the same version, whatever their source.
+type DatasetVersion struct {
+	Version    string    `json:"version"`    // Short form of the hash
+	Hash       string    `json:"hash"`       // sha256 of the airports in ICAO order
+	Generation uint64    `json:"generation"` // Generation that last served this version
+	Airports   int       `json:"airports"`
+	Source     string    `json:"source"`
+	CreatedAt  time.Time `json:"created_at"` // When the version was first served
+}
+
+// DatasetChanges lists how the served dataset differs from an earlier
+// version. Airports are ordered by ICAO code.
+type DatasetChanges struct {
+	Since    DatasetVersion
+	Current  DatasetVersion
+	Added    []*Airport
+	Modified []*Airport
+	Removed  []string // ICAO codes
+}
+
+// datasetSnapshot is a retained version with a digest of each airport, so
+// versions can be compared without keeping their airports
+type datasetSnapshot struct {
+	DatasetVersion
+	digests map[string]uint64
+}
+
+// newSnapshot hashes a dataset
+func newSnapshot(data AirportDatabase) *datasetSnapshot {
+	icaos := make([]string, 0, len(data))
+	for icao := range data {
+		icaos = append(icaos, icao)
+	}
+	sort.Strings(icaos)
+
+	digests := make(map[string]uint64, len(data))
+	h := sha256.New()
+	for _, icao := range icaos {
+		apt := data[icao]
+		raw, _ := json.Marshal(&apt)
+		sum := sha256.Sum256(raw)
+		digests[icao] = binary.BigEndian.Uint64(sum[:8])
+		h.Write([]byte(icao))
+		h.Write(sum[:])
+	}
+
+	hash := hex.EncodeToString(h.Sum(nil))
+	return &datasetSnapshot{
+		DatasetVersion: DatasetVersion{
+			Version:  hash[:16],
+			Hash:     "sha256:" + hash,
+			Airports: len(data),
+		},
+		digests: digests,
+	}
+}
+
+// recordVersion makes snap the current version. A dataset identical to a
+// retained version takes that version's place instead of adding another.
+// The caller holds indexes.mu for writing and has advanced the generation.
+func (s *Service) recordVersion(snap *datasetSnapshot, now time.Time) {
+	snap.Generation = s.generation
+	snap.Source = s.source
+	snap.CreatedAt = now
+
+	versions := make([]*datasetSnapshot, 0, len(s.versions)+1)
+	for _, v := range s.versions {
+		if v.Hash == snap.Hash {
+			if v == s.versions[len(s.versions)-1] {
+				// Unchanged: still first served when it was created
+				snap.CreatedAt = v.CreatedAt
+			}
+			continue
+		}
+		versions = append(versions, v)
+	}
+	versions = append(versions, snap)
+	if len(versions) > retainedVersions {
+		versions = versions[len(versions)-retainedVersions:]
+	}
+	s.versions = versions
+}
+
+// Version returns the version of the served dataset
+func (s *Service) Version() DatasetVersion {
+	s.indexes.mu.RLock()
+	defer s.indexes.mu.RUnlock()
+
+	return s.currentVersion()
+}
+
+// currentVersion returns the served version, or none before the first
+// load. The caller holds indexes.mu.
+func (s *Service) currentVersion() DatasetVersion {
+	if len(s.versions) == 0 {
+		return DatasetVersion{}
+	}
+	return s.versions[len(s.versions)-1].DatasetVersion
+}
+
+// Versions returns the retained dataset versions, oldest first. The last
+// is the served dataset.
+func (s *Service) Versions() []DatasetVersion {
+	s.indexes.mu.RLock()
+	defer s.indexes.mu.RUnlock()
+
+	list := make([]DatasetVersion, len(s.versions))
+	for i, v := range s.versions {
+		list[i] = v.DatasetVersion
+	}
+	return list
+}
+
+// Changes lists the airports added, removed and modified since a retained
+// version. Added and modified airports are returned as currently served.
+func (s *Service) Changes(since string) (*DatasetChanges, error) {
+	s.indexes.mu.RLock()
+	defer s.indexes.mu.RUnlock()
+
+	var old *datasetSnapshot
+	for _, v := range s.versions {
+		if v.Version == since {
+			old = v
+		}
+	}
+	if old == nil {
+		return nil, ErrUnknownVersion
+	}
+	current := s.versions[len(s.versions)-1]
+
+	changes := &DatasetChanges{
+		Since:    old.DatasetVersion,
+		Current:  current.DatasetVersion,
+		Added:    []*Airport{},
+		Modified: []*Airport{},
+		Removed:  []string{},
+	}
+	for icao, digest := range current.digests {
+		previous, ok := old.digests[icao]
+		if ok && previous == digest {
+			continue
+		}
+		apt := s.data[icao]
+		if ok {
+			changes.Modified = append(changes.Modified, &apt)
+		} else {
+			changes.Added = append(changes.Added, &apt)
+		}
+	}
+	for icao := range old.digests {
+		if _, ok := current.digests[icao]; !ok {
+			changes.Removed = append(changes.Removed, icao)
+		}
+	}
+
+	sort.Slice(changes.Added, func(i, j int) bool { return changes.Added[i].ICAO < changes.Added[j].ICAO })
+	sort.Slice(changes.Modified, func(i, j int) bool { return changes.Modified[i].ICAO < changes.Modified[j].ICAO })
+	sort.Strings(changes.Removed)
+	return changes, nil
+}
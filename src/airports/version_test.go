@@ -0,0 +1,95 @@
+package airports
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestDatasetVersionChanges(t *testing.T) {
+	svc := newOverrideTestService(t)
+	first := svc.Version()
+	if len(first.Version) != 16 || first.Hash == "" || first.Airports != 2 {
+		t.Fatalf("unexpected initial version: %+v", first)
+	}
+
+	// The same airports give the same version
+	other, err := NewService([]byte(overrideTestData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Version().Hash != first.Hash {
+		t.Errorf("expected identical datasets to share a hash")
+	}
+
+	if _, err := svc.PatchAirport("KJFK", json.RawMessage(`{"name": "Kennedy"}`), nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.DeleteAirport("KLGA", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.CreateAirport("XTST", json.RawMessage(`{"name": "Test Field", "lat": 1, "lon": 2}`), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := svc.Changes(first.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes.Current.Version == first.Version {
+		t.Errorf("expected a new version after the changes")
+	}
+	if !equalStrings(icaosOf(changes.Added), []string{"XTST"}) ||
+		!equalStrings(icaosOf(changes.Modified), []string{"KJFK"}) ||
+		!equalStrings(changes.Removed, []string{"KLGA"}) {
+		t.Errorf("unexpected changes: added %v, modified %v, removed %v",
+			icaosOf(changes.Added), icaosOf(changes.Modified), changes.Removed)
+	}
+	if changes.Modified[0].Name != "Kennedy" {
+		t.Errorf("expected the airport as served, got %q", changes.Modified[0].Name)
+	}
+
+	current, err := svc.Changes(changes.Current.Version)
+	if err != nil || len(current.Added)+len(current.Modified)+len(current.Removed) != 0 {
+		t.Errorf("expected no changes since the current version, got %+v %v", current, err)
+	}
+	if _, err := svc.Changes("0123456789abcdef"); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("expected ErrUnknownVersion, got %v", err)
+	}
+
+	// Reverting every change returns to the first version without
+	// retaining it twice
+	for _, icao := range []string{"KJFK", "KLGA", "XTST"} {
+		if _, err := svc.RevertAirport(icao, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if svc.Version().Hash != first.Hash {
+		t.Errorf("expected the first version after reverting")
+	}
+	versions := svc.Versions()
+	for _, v := range versions[:len(versions)-1] {
+		if v.Hash == first.Hash {
+			t.Errorf("version %s retained twice", first.Version)
+		}
+	}
+}
+
+func TestRetainedVersions(t *testing.T) {
+	svc := newOverrideTestService(t)
+	first := svc.Version()
+
+	for i := 0; i < retainedVersions; i++ {
+		fields, _ := json.Marshal(map[string]int{"elevation": i + 1})
+		if _, err := svc.PatchAirport("KJFK", fields, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := len(svc.Versions()); n != retainedVersions {
+		t.Errorf("retained %d versions, want %d", n, retainedVersions)
+	}
+	if _, err := svc.Changes(first.Version); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("expected the oldest version to be dropped, got %v", err)
+	}
+}
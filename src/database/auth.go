@@ -107,6 +107,11 @@ func ValidateToken(token string) bool {
 	return hashToken(token) == storedHash
 }
 
+// TokenID identifies a token in audit records without revealing it
+func TokenID(token string) string {
+	return hashToken(token)[:12]
+}
+
 // UpdateAdminPassword updates the admin password
 func UpdateAdminPassword(newPassword string) error {
 	passwordHash := hashPassword(newPassword)
@@ -72,6 +72,9 @@ func Initialize(config Config) error {
 		return fmt.Errorf("failed to initialize schema: %w", err)
 	}
 
+	// Values cached from a previous connection no longer apply
+	InvalidateSettingsCache()
+
 	return nil
 }
 
@@ -0,0 +1,136 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// AirportOverride is a stored correction to one airport of the dataset
+type AirportOverride struct {
+	ICAO      string          `json:"icao"`
+	Action    string          `json:"action"`           // replace, patch or delete
+	Fields    json.RawMessage `json:"fields,omitempty"` // Airport JSON
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// AirportOverrideChange is one entry of the airport override audit history
+type AirportOverrideChange struct {
+	ID         int64           `json:"id"`
+	ICAO       string          `json:"icao"`
+	Operation  string          `json:"operation"`
+	Before     json.RawMessage `json:"before"`                // Airport before the change; null when absent
+	After      json.RawMessage `json:"after"`                 // Airport after the change; null when absent
+	Actor      string          `json:"actor,omitempty"`       // Admin username, or "token:" and the token ID
+	RemoteAddr string          `json:"remote_addr,omitempty"` // Address the change was made from
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// GetAirportOverrides retrieves every airport override
+func GetAirportOverrides() ([]*AirportOverride, error) {
+	rows, err := DB.Query(`
+		SELECT icao, action, fields, updated_at
+		FROM airport_overrides
+		ORDER BY icao
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var overrides []*AirportOverride
+	for rows.Next() {
+		o := &AirportOverride{}
+		var fields sql.NullString
+		if err := rows.Scan(&o.ICAO, &o.Action, &fields, &o.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if fields.Valid {
+			o.Fields = json.RawMessage(fields.String)
+		}
+		overrides = append(overrides, o)
+	}
+
+	return overrides, rows.Err()
+}
+
+// SaveAirportOverride stores an override, or removes the override for
+// change.ICAO when o is nil, and records the change in the audit history
+// in the same transaction
+func SaveAirportOverride(o *AirportOverride, change *AirportOverrideChange) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if o == nil {
+		_, err = tx.Exec("DELETE FROM airport_overrides WHERE icao = ?", change.ICAO)
+	} else {
+		_, err = tx.Exec(`
+			INSERT INTO airport_overrides (icao, action, fields, updated_at)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT(icao) DO UPDATE SET
+				action = excluded.action,
+				fields = excluded.fields,
+				updated_at = excluded.updated_at
+		`, o.ICAO, o.Action, nullJSON(o.Fields), o.UpdatedAt)
+	}
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO airport_override_history (icao, operation, before_json, after_json, actor, remote_addr, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, change.ICAO, change.Operation, nullJSON(change.Before), nullJSON(change.After), change.Actor, change.RemoteAddr, change.CreatedAt)
+	if err != nil {
+		return err
+	}
+	if change.ID, err = result.LastInsertId(); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetAirportOverrideHistory retrieves the audit history of an airport's
+// overrides, newest first
+func GetAirportOverrideHistory(icao string, limit int) ([]*AirportOverrideChange, error) {
+	rows, err := DB.Query(`
+		SELECT id, icao, operation, before_json, after_json, actor, remote_addr, created_at
+		FROM airport_override_history WHERE icao = ?
+		ORDER BY id DESC
+		LIMIT ?
+	`, icao, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []*AirportOverrideChange{}
+	for rows.Next() {
+		c := &AirportOverrideChange{}
+		var before, after sql.NullString
+		if err := rows.Scan(&c.ID, &c.ICAO, &c.Operation, &before, &after, &c.Actor, &c.RemoteAddr, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		if before.Valid {
+			c.Before = json.RawMessage(before.String)
+		}
+		if after.Valid {
+			c.After = json.RawMessage(after.String)
+		}
+		changes = append(changes, c)
+	}
+
+	return changes, rows.Err()
+}
+
+// nullJSON stores empty JSON as NULL
+func nullJSON(raw json.RawMessage) interface{} {
+	if len(raw) == 0 {
+		return nil
+	}
+	return string(raw)
+}
@@ -17,14 +17,41 @@ INSERT OR IGNORE INTO settings (key, value, type, category, description) VALUES
     ('server.timezone', 'UTC', 'string', 'server', 'Server timezone'),
     ('server.date_format', 'US', 'string', 'server', 'Date format (US/EU/ISO)'),
     ('server.time_format', '12-hour', 'string', 'server', 'Time format (12-hour/24-hour)'),
-    ('server.default_units', 'imperial', 'string', 'server', 'Default unit system (imperial/metric)'),
+    ('server.default_units', 'imperial', 'string', 'server', 'Default unit system (imperial/metric/nautical)'),
+    ('server.trusted_proxies', '', 'string', 'server', 'Comma-separated proxy addresses or CIDR ranges whose X-Forwarded-For and X-Real-IP headers are trusted'),
     ('api.rate_limit_enabled', 'false', 'boolean', 'api', 'Enable API rate limiting'),
     ('api.rate_limit_requests', '100', 'number', 'api', 'Requests per minute per IP'),
+    ('api.rate_limit_keys', '', 'string', 'api', 'Comma-separated API keys rate limited per key instead of per IP'),
     ('api.cors_enabled', 'true', 'boolean', 'api', 'Enable CORS'),
     ('api.cors_origin', '*', 'string', 'api', 'CORS allowed origins'),
     ('features.geoip_enabled', 'true', 'boolean', 'features', 'Enable GeoIP lookups'),
     ('features.nearby_max_radius', '500', 'number', 'features', 'Maximum radius for nearby searches (km)'),
-    ('features.search_max_results', '1000', 'number', 'features', 'Maximum search results');
+    ('features.search_max_results', '1000', 'number', 'features', 'Maximum search results'),
+    ('cache.api_control', 'public, max-age=300', 'string', 'cache', 'Cache-Control for airport API responses'),
+    ('cache.export_control', 'public, max-age=3600', 'string', 'cache', 'Cache-Control for dataset exports and vector tiles'),
+    ('cache.compression', 'true', 'boolean', 'cache', 'Compress responses with gzip or brotli');
 
 -- Index for faster lookups
 CREATE INDEX IF NOT EXISTS idx_settings_category ON settings(category);
+
+-- Airport overrides (administrator corrections merged over the airport dataset)
+CREATE TABLE IF NOT EXISTS airport_overrides (
+    icao TEXT PRIMARY KEY,
+    action TEXT NOT NULL CHECK (action IN ('replace', 'patch', 'delete')),
+    fields TEXT,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Audit history of airport override changes
+CREATE TABLE IF NOT EXISTS airport_override_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    icao TEXT NOT NULL,
+    operation TEXT NOT NULL,
+    before_json TEXT,
+    after_json TEXT,
+    actor TEXT NOT NULL DEFAULT '',
+    remote_addr TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_airport_override_history_icao ON airport_override_history(icao, id);
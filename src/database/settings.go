@@ -3,8 +3,10 @@ package database
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
+	"sync"
 	"time"
 )
 
@@ -18,6 +20,9 @@ type Setting struct {
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+// ErrSettingNotFound is returned by GetSetting for an unknown key
+var ErrSettingNotFound = errors.New("setting not found")
+
 // GetSetting retrieves a setting by key
 func GetSetting(key string) (*Setting, error) {
 	setting := &Setting{}
@@ -27,7 +32,7 @@ func GetSetting(key string) (*Setting, error) {
 	`, key).Scan(&setting.Key, &setting.Value, &setting.Type, &setting.Category, &setting.Description, &setting.UpdatedAt)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("setting not found: %s", key)
+		return nil, fmt.Errorf("%w: %s", ErrSettingNotFound, key)
 	}
 	if err != nil {
 		return nil, err
@@ -36,22 +41,98 @@ func GetSetting(key string) (*Setting, error) {
 	return setting, nil
 }
 
+// settingsCache holds setting values read through the value getters, so
+// request handlers can consult settings without a query each time. Writes
+// through SetSetting, DeleteSetting and ResetToDefaults invalidate it.
+var settingsCache = struct {
+	sync.RWMutex
+	values     map[string]*string // nil marks a setting known to be missing
+	generation uint64             // Bumped by every invalidation
+}{values: make(map[string]*string)}
+
+// loadSetting reads a setting on a cache miss. Tests replace it to
+// interleave invalidations with loads.
+var loadSetting = GetSetting
+
+// cachedSettingValue returns a setting's value from the cache, loading it
+// on a miss. It reports false when the setting does not exist or there is
+// no database.
+func cachedSettingValue(key string) (string, bool) {
+	settingsCache.RLock()
+	value, ok := settingsCache.values[key]
+	generation := settingsCache.generation
+	settingsCache.RUnlock()
+	if ok {
+		if value == nil {
+			return "", false
+		}
+		return *value, true
+	}
+
+	if DB == nil {
+		return "", false
+	}
+
+	setting, err := loadSetting(key)
+	if err != nil && !errors.Is(err, ErrSettingNotFound) {
+		// Don't cache transient errors
+		return "", false
+	}
+	if setting != nil {
+		value = &setting.Value
+	}
+
+	// A value read before a concurrent invalidation may already be stale,
+	// so it is only cached if the cache was not invalidated meanwhile
+	settingsCache.Lock()
+	if settingsCache.generation == generation {
+		settingsCache.values[key] = value
+	}
+	settingsCache.Unlock()
+
+	if value == nil {
+		return "", false
+	}
+	return *value, true
+}
+
+// InvalidateSettingsCache drops all cached setting values
+func InvalidateSettingsCache() {
+	settingsCache.Lock()
+	settingsCache.values = make(map[string]*string)
+	settingsCache.generation++
+	settingsCache.Unlock()
+}
+
 // GetSettingValue retrieves just the value as a string
 func GetSettingValue(key, defaultValue string) string {
-	setting, err := GetSetting(key)
-	if err != nil {
+	value, ok := cachedSettingValue(key)
+	if !ok {
 		return defaultValue
 	}
-	return setting.Value
+	return value
 }
 
 // GetSettingInt retrieves a setting as an integer
 func GetSettingInt(key string, defaultValue int) int {
-	setting, err := GetSetting(key)
+	value, ok := cachedSettingValue(key)
+	if !ok {
+		return defaultValue
+	}
+	val, err := strconv.Atoi(value)
 	if err != nil {
 		return defaultValue
 	}
-	val, err := strconv.Atoi(setting.Value)
+	return val
+}
+
+// GetSettingFloat retrieves a setting as a float
+func GetSettingFloat(key string, defaultValue float64) float64 {
+	value, ok := cachedSettingValue(key)
+	if !ok {
+		return defaultValue
+	}
+	val, err := strconv.ParseFloat(value, 64)
 	if err != nil {
 		return defaultValue
 	}
@@ -60,11 +141,11 @@ func GetSettingInt(key string, defaultValue int) int {
 
 // GetSettingBool retrieves a setting as a boolean
 func GetSettingBool(key string, defaultValue bool) bool {
-	setting, err := GetSetting(key)
-	if err != nil {
+	value, ok := cachedSettingValue(key)
+	if !ok {
 		return defaultValue
 	}
-	val, err := strconv.ParseBool(setting.Value)
+	val, err := strconv.ParseBool(value)
 	if err != nil {
 		return defaultValue
 	}
@@ -156,17 +237,21 @@ func SetSetting(key, value, settingType, category, description string) error {
 			updated_at = CURRENT_TIMESTAMP
 	`, key, value, settingType, category, description)
 
+	InvalidateSettingsCache()
 	return err
 }
 
 // DeleteSetting removes a setting
 func DeleteSetting(key string) error {
 	_, err := DB.Exec("DELETE FROM settings WHERE key = ?", key)
+	InvalidateSettingsCache()
 	return err
 }
 
 // ResetToDefaults resets all settings to default values
 func ResetToDefaults() error {
+	defer InvalidateSettingsCache()
+
 	// Delete all settings
 	if _, err := DB.Exec("DELETE FROM settings"); err != nil {
 		return err
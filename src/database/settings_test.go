@@ -0,0 +1,40 @@
+package database
+
+import (
+	"testing"
+)
+
+func TestSettingsCacheInvalidatedDuringLoad(t *testing.T) {
+	if err := Initialize(Config{Path: t.TempDir() + "/airports.db"}); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() {
+		Close()
+		DB = nil
+		InvalidateSettingsCache()
+	}()
+
+	const key = "server.default_units"
+	if err := SetSetting(key, "metric", "string", "server", "Default unit system (imperial/metric/nautical)"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The setting changes after the cache miss has read the old value but
+	// before the value is cached
+	defer func() { loadSetting = GetSetting }()
+	loadSetting = func(key string) (*Setting, error) {
+		setting, err := GetSetting(key)
+		if err := SetSetting(key, "nautical", "string", "server", "Default unit system (imperial/metric/nautical)"); err != nil {
+			t.Fatal(err)
+		}
+		loadSetting = GetSetting
+		return setting, err
+	}
+
+	if got := GetSettingValue(key, ""); got != "metric" {
+		t.Errorf("Expected the value read before the change, got %q", got)
+	}
+	if got := GetSettingValue(key, ""); got != "nautical" {
+		t.Errorf("Expected the changed value after the invalidation, got %q", got)
+	}
+}
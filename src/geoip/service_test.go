@@ -15,7 +15,7 @@ func TestNewService(t *testing.T) {
 	}
 	defer svc.Close()
 
-	if svc.cityDB == nil {
+	if svc.cityIPv4DB == nil {
 		t.Error("City database not loaded")
 	}
 
@@ -29,7 +29,7 @@ func TestNewService(t *testing.T) {
 
 	// Verify files were downloaded
 	geoipDir := filepath.Join(tmpDir, "geoip")
-	if _, err := os.Stat(filepath.Join(geoipDir, "GeoLite2-City.mmdb")); err != nil {
+	if _, err := os.Stat(filepath.Join(geoipDir, "geolite2-city-ipv4.mmdb")); err != nil {
 		t.Error("City database file not found")
 	}
 }
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/apimgr/airports/src/airports"
+)
+
+// importSkippedShown is how many skipped rows the import summary lists
+const importSkippedShown = 20
+
+// runImport converts a public airport dataset to the JSON the server loads
+// from AIRPORTS_DATA
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	format := fs.String("format", airports.FormatOurAirports, "Input format: ourairports or openflights")
+	input := fs.String("input", "", "OurAirports directory or airports.csv, or OpenFlights airports.dat")
+	output := fs.String("output", "", "Output JSON file (default: stdout)")
+	skippedFile := fs.String("skipped", "", "Write every skipped row to this JSON file")
+	includeClosed := fs.Bool("include-closed", false, "Keep closed airports (OurAirports)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: airports import --format FORMAT --input PATH [--output FILE]")
+		fmt.Fprintln(fs.Output())
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *input == "" {
+		fs.Usage()
+		return fmt.Errorf("--input is required")
+	}
+
+	res, err := airports.Import(*format, *input, airports.ImportOptions{IncludeClosed: *includeClosed})
+	if err != nil {
+		return fmt.Errorf("import failed: %w", err)
+	}
+
+	if *output == "" {
+		if err := writeAirportsJSON(os.Stdout, res.Airports); err != nil {
+			return err
+		}
+	} else if err := writeFileAtomic(*output, func(w io.Writer) error {
+		return writeAirportsJSON(w, res.Airports)
+	}); err != nil {
+		return err
+	}
+
+	if *skippedFile != "" {
+		if err := writeFileAtomic(*skippedFile, func(w io.Writer) error {
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			return enc.Encode(res.Skipped)
+		}); err != nil {
+			return err
+		}
+	}
+
+	// The summary goes to stderr so stdout stays valid JSON
+	runways, frequencies := 0, 0
+	for _, apt := range res.Airports {
+		runways += len(apt.Runways)
+		frequencies += len(apt.Frequencies)
+	}
+	fmt.Fprintf(os.Stderr, "Read %d rows: %d airports, %d runways, %d frequencies; skipped %d rows\n",
+		res.Rows, len(res.Airports), runways, frequencies, len(res.Skipped))
+	for i, row := range res.Skipped {
+		if i == importSkippedShown {
+			fmt.Fprintf(os.Stderr, "  ... and %d more (use --skipped to list them all)\n", len(res.Skipped)-i)
+			break
+		}
+		fmt.Fprintf(os.Stderr, "  %s:%d: %s\n", row.File, row.Line, row.Reason)
+	}
+	if *output != "" {
+		fmt.Fprintf(os.Stderr, "Wrote %s\n", *output)
+	}
+
+	return nil
+}
+
+// writeAirportsJSON writes airports as an object keyed by ICAO code
+func writeAirportsJSON(w io.Writer, data airports.AirportDatabase) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", " ")
+	return enc.Encode(data)
+}
+
+// writeFileAtomic writes a file through a temporary file and a rename, so
+// a server watching it never reads a partial write
+func writeFileAtomic(path string, write func(io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -33,6 +33,14 @@ var (
 )
 
 func main() {
+	// Subcommands
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		if err := runImport(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	// Command line flags
 	portFlag := flag.String("port", "", "HTTP port")
 	showVersion := flag.Bool("version", false, "Show version and exit")
@@ -185,14 +193,53 @@ func run(port string, devMode bool) error {
 		}
 	}
 
-	// Load airport data
+	// Load airport data: an external file or directory when present,
+	// otherwise the embedded copy
 	log.Println("Loading airport database...")
-	airportSvc, err := airports.NewService(airportsData)
+	airportsPath := getEnv("AIRPORTS_DATA", fmt.Sprintf("%s/airports.json", dataDir))
+	airportSvc, err := airports.NewServiceFromPath(airportsPath, airportsData)
 	if err != nil {
 		return fmt.Errorf("failed to load airports: %w", err)
 	}
 	stats := airportSvc.Stats()
-	log.Printf("Loaded %d airports from %d countries", stats["total_airports"], stats["countries"])
+	source := stats["dataset"].(map[string]interface{})["source"]
+	log.Printf("Loaded %d airports from %d countries (%s)", stats["total_airports"], stats["countries"], source)
+
+	// Apply administrator corrections over the dataset
+	overrides, err := server.LoadAirportOverrides(airportSvc)
+	if err != nil {
+		return fmt.Errorf("failed to load airport overrides: %w", err)
+	}
+	if overrides > 0 {
+		log.Printf("Applied %d airport overrides", overrides)
+	}
+
+	// Reload the dataset when its files change
+	watchInterval, err := time.ParseDuration(getEnv("AIRPORTS_WATCH_INTERVAL", "30s"))
+	if err != nil {
+		return fmt.Errorf("invalid AIRPORTS_WATCH_INTERVAL: %w", err)
+	}
+	stopWatch := make(chan struct{})
+	defer close(stopWatch)
+	if watchInterval > 0 {
+		go airportSvc.Watch(watchInterval, stopWatch)
+		log.Printf("Watching %s for airport data changes every %s", airportsPath, watchInterval)
+	}
+
+	// Reload the dataset on SIGHUP
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go func() {
+		for range hup {
+			result, err := airportSvc.Reload(airports.TriggerSignal)
+			if err != nil {
+				log.Printf("Airports: reload failed, keeping generation %d: %v", result.Generation, err)
+				continue
+			}
+			log.Printf("Airports: reloaded %d airports from %s (generation %d)", result.Airports, result.Source, result.Generation)
+		}
+	}()
 
 	// Load GeoIP data
 	log.Println("Loading GeoIP databases...")
@@ -257,6 +304,11 @@ func printHelp() {
 	fmt.Println("airports - Airport location information API server")
 	fmt.Println()
 	fmt.Println("Usage: airports [OPTIONS]")
+	fmt.Println("       airports import --format FORMAT --input PATH [--output FILE]")
+	fmt.Println()
+	fmt.Println("Commands:")
+	fmt.Println("  import            Convert OurAirports CSV (ourairports) or OpenFlights")
+	fmt.Println("                    airports.dat (openflights) to airport JSON for AIRPORTS_DATA")
 	fmt.Println()
 	fmt.Println("Options:")
 	fmt.Println("  --help            Show this help message")
@@ -273,6 +325,10 @@ func printHelp() {
 	fmt.Println("  CONFIG_DIR        Config directory path")
 	fmt.Println("  DATA_DIR          Data directory path")
 	fmt.Println("  LOGS_DIR          Logs directory path")
+	fmt.Println("  AIRPORTS_DATA     Airport JSON file or directory (default: {DATA_DIR}/airports.json,")
+	fmt.Println("                    embedded data when missing; reload with SIGHUP)")
+	fmt.Println("  AIRPORTS_WATCH_INTERVAL")
+	fmt.Println("                    Poll interval for airport data changes (default: 30s, 0 disables)")
 	fmt.Println("  PORT              Server port")
 	fmt.Println("  ADDRESS           Listen address")
 	fmt.Println()
@@ -293,6 +349,7 @@ func printHelp() {
 	fmt.Println("  airports --port 8080              # Start on port 8080")
 	fmt.Println("  airports --data /var/lib/airports # Use custom data directory")
 	fmt.Println("  airports --dev                    # Start in development mode")
+	fmt.Println("  airports import --input ./ourairports --output /var/lib/airports/airports.json")
 	fmt.Println()
 	fmt.Println("Admin Panel:")
 	fmt.Println("  Web UI:  http://<your-host>:<port>/admin")
@@ -2,11 +2,37 @@ package server
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
 
+	"github.com/apimgr/airports/src/airports"
 	"github.com/apimgr/airports/src/database"
 )
 
+// normalizeSetting validates a setting value beyond its type, returning
+// the value to store
+func normalizeSetting(key, value string) (string, error) {
+	switch key {
+	case settingDefaultUnits:
+		if !airports.ValidUnits(value) {
+			return value, fmt.Errorf("invalid value for %s (must be imperial, metric or nautical)", key)
+		}
+		return airports.ParseUnits(value), nil
+	case settingCacheAPI, settingCacheExport:
+		if strings.ContainsAny(value, "\r\n") {
+			return value, fmt.Errorf("invalid value for %s (must be a single line)", key)
+		}
+		return strings.TrimSpace(value), nil
+	case settingTrustedProxies:
+		if _, err := parseNetworks(value); err != nil {
+			return value, fmt.Errorf("invalid value for %s: %v (must be comma-separated IP addresses or CIDR ranges)", key, err)
+		}
+		return strings.TrimSpace(value), nil
+	}
+	return value, nil
+}
+
 // Web UI Handlers (Basic Auth)
 
 // handleAdminDashboard shows the admin dashboard
@@ -50,6 +76,12 @@ func (s *Server) handleAdminSettingsUpdate(w http.ResponseWriter, r *http.Reques
 			continue // Skip unknown settings
 		}
 
+		value, err = normalizeSetting(key, value)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		// Update setting
 		err = database.SetSetting(key, value, existing.Type, existing.Category, existing.Description)
 		if err != nil {
@@ -153,6 +185,12 @@ func (s *Server) handleAdminSettingsUpdateAPI(w http.ResponseWriter, r *http.Req
 			return
 		}
 
+		value, err = normalizeSetting(key, value)
+		if err != nil {
+			s.respondFieldError(w, http.StatusBadRequest, "INVALID_SETTING", err.Error(), key)
+			return
+		}
+
 		// Update setting
 		err = database.SetSetting(key, value, existing.Type, existing.Category, existing.Description)
 		if err != nil {
@@ -202,6 +240,19 @@ func (s *Server) handleAdminLogsAPI(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleAdminAirportsReloadAPI reloads the airport dataset from disk. On
+// failure the current dataset keeps serving.
+func (s *Server) handleAdminAirportsReloadAPI(w http.ResponseWriter, r *http.Request) {
+	result, err := s.airports.Reload(airports.TriggerAdmin)
+	if err != nil {
+		s.respondError(w, http.StatusUnprocessableEntity, "RELOAD_FAILED",
+			fmt.Sprintf("Reload failed, still serving generation %d: %v", result.Generation, err))
+		return
+	}
+
+	s.respondJSON(w, http.StatusOK, result)
+}
+
 // handleAdminHealthAPI returns detailed health status
 func (s *Server) handleAdminHealthAPI(w http.ResponseWriter, r *http.Request) {
 	health := map[string]interface{}{
@@ -11,61 +11,78 @@ import (
 
 type contextKey string
 
-const adminAuthKey contextKey = "admin_authenticated"
+const (
+	adminAuthKey     contextKey = "admin_authenticated"
+	adminIdentityKey contextKey = "admin_identity"
+)
 
 // AdminAuthMiddleware checks for valid admin authentication
 // Supports both Bearer token (API) and Basic auth (Web UI)
 func AdminAuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Check Authorization header
-		authHeader := r.Header.Get("Authorization")
+		if identity, ok := authenticateAdmin(r); ok {
+			ctx := context.WithValue(r.Context(), adminAuthKey, true)
+			ctx = context.WithValue(ctx, adminIdentityKey, identity)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
 
-		if authHeader != "" {
-			// Try Bearer token first (API)
-			if strings.HasPrefix(authHeader, "Bearer ") {
-				token := strings.TrimPrefix(authHeader, "Bearer ")
-				if database.ValidateToken(token) {
-					ctx := context.WithValue(r.Context(), adminAuthKey, true)
-					next.ServeHTTP(w, r.WithContext(ctx))
-					return
-				}
+		// No valid authentication found
+		w.Header().Set("WWW-Authenticate", `Basic realm="Admin Area"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	})
+}
+
+// authenticateAdmin checks a request for a valid admin Bearer token, Basic
+// auth credentials or session cookie. It returns who authenticated: the
+// username for Basic auth, otherwise "token:" and the token's ID.
+func authenticateAdmin(r *http.Request) (identity string, ok bool) {
+	// Check Authorization header
+	authHeader := r.Header.Get("Authorization")
+
+	if authHeader != "" {
+		// Try Bearer token first (API)
+		if strings.HasPrefix(authHeader, "Bearer ") {
+			token := strings.TrimPrefix(authHeader, "Bearer ")
+			if database.ValidateToken(token) {
+				return "token:" + database.TokenID(token), true
 			}
+		}
 
-			// Try Basic auth (Web UI)
-			if strings.HasPrefix(authHeader, "Basic ") {
-				payload := strings.TrimPrefix(authHeader, "Basic ")
-				decoded, err := base64.StdEncoding.DecodeString(payload)
-				if err == nil {
-					parts := strings.SplitN(string(decoded), ":", 2)
-					if len(parts) == 2 {
-						username := parts[0]
-						password := parts[1]
+		// Try Basic auth (Web UI)
+		if strings.HasPrefix(authHeader, "Basic ") {
+			payload := strings.TrimPrefix(authHeader, "Basic ")
+			decoded, err := base64.StdEncoding.DecodeString(payload)
+			if err == nil {
+				parts := strings.SplitN(string(decoded), ":", 2)
+				if len(parts) == 2 {
+					username := parts[0]
+					password := parts[1]
 
-						// Validate credentials
-						storedUsername := database.GetSettingValue("admin.username", "administrator")
-						if username == storedUsername && database.ValidatePassword(password) {
-							ctx := context.WithValue(r.Context(), adminAuthKey, true)
-							next.ServeHTTP(w, r.WithContext(ctx))
-							return
-						}
+					// Validate credentials
+					storedUsername := database.GetSettingValue("admin.username", "administrator")
+					if username == storedUsername && database.ValidatePassword(password) {
+						return username, true
 					}
 				}
 			}
 		}
+	}
 
-		// Check for session cookie (after successful Basic auth)
-		if cookie, err := r.Cookie("admin_session"); err == nil && cookie.Value != "" {
-			if database.ValidateToken(cookie.Value) {
-				ctx := context.WithValue(r.Context(), adminAuthKey, true)
-				next.ServeHTTP(w, r.WithContext(ctx))
-				return
-			}
+	// Check for session cookie (after successful Basic auth)
+	if cookie, err := r.Cookie("admin_session"); err == nil && cookie.Value != "" {
+		if database.ValidateToken(cookie.Value) {
+			return "token:" + database.TokenID(cookie.Value), true
 		}
+	}
 
-		// No valid authentication found
-		w.Header().Set("WWW-Authenticate", `Basic realm="Admin Area"`)
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-	})
+	return "", false
+}
+
+// adminIdentity returns who AdminAuthMiddleware authenticated the request as
+func adminIdentity(r *http.Request) string {
+	identity, _ := r.Context().Value(adminIdentityKey).(string)
+	return identity
 }
 
 // IsAdminAuthenticated checks if the request is authenticated
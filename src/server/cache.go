@@ -0,0 +1,126 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/apimgr/airports/src/airports"
+)
+
+// cached serves GET requests for responses derived only from the dataset,
+// the request and the query settings. They get a strong ETag, Last-Modified
+// and the Cache-Control policy in setting, and conditional requests that
+// still match are answered with 304 Not Modified. Error responses are sent
+// without cache headers.
+func (s *Server) cached(setting, fallback string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Read the version first: if a reload lands before the handler
+			// runs, the tag is older than the content and the next request
+			// fetches it again
+			version := s.airports.Version()
+			etag := requestETag(version, r)
+			modified := version.CreatedAt.UTC().Truncate(time.Second)
+
+			// The tag depends on Accept, so every response says so,
+			// 304s included
+			h := w.Header()
+			addVary(h, "Accept")
+			h.Set("ETag", etag)
+			if !modified.IsZero() {
+				h.Set("Last-Modified", modified.Format(http.TimeFormat))
+			}
+			if policy := cacheControl(setting, fallback); policy != "" {
+				h.Set("Cache-Control", policy)
+			}
+
+			if notModified(r, etag, modified) {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			next.ServeHTTP(&cacheWriter{ResponseWriter: w}, r)
+		})
+	}
+}
+
+// requestETag tags a response by the dataset version and everything else
+// it depends on: the path, the query parameters, the Accept header for
+// negotiated formats and the settings that shape query results
+func requestETag(version airports.DatasetVersion, r *http.Request) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n%s\n", version.Hash, r.URL.Path, r.URL.Query().Encode(), r.Header.Get("Accept"))
+	fmt.Fprintf(h, "%s\n%g\n%d\n", defaultUnits(), nearbyMaxRadius(), searchMaxResults())
+	return `"` + version.Version + "-" + hex.EncodeToString(h.Sum(nil))[:16] + `"`
+}
+
+// notModified evaluates a request's If-None-Match header, or its
+// If-Modified-Since header when there is none, against the current response.
+// "*" matches only a representation that exists, which is not known until
+// the handler has run, so it never answers with 304 here.
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			if sameETag(strings.TrimSpace(candidate), etag) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !modified.IsZero() {
+		since, err := http.ParseTime(ims)
+		return err == nil && !modified.After(since)
+	}
+	return false
+}
+
+// sameETag compares entity tags weakly, as If-None-Match requires. Tags of
+// compressed representations match the uncompressed tag.
+func sameETag(a, b string) bool {
+	return stripEncodingTag(strings.TrimPrefix(a, "W/")) == stripEncodingTag(strings.TrimPrefix(b, "W/"))
+}
+
+// cacheWriter drops the cache headers from error responses
+type cacheWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (cw *cacheWriter) WriteHeader(status int) {
+	if !cw.wroteHeader {
+		cw.wroteHeader = true
+		if status >= http.StatusBadRequest {
+			h := cw.Header()
+			h.Del("ETag")
+			h.Del("Last-Modified")
+			h.Set("Cache-Control", "no-store")
+		}
+	}
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+func (cw *cacheWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+func (cw *cacheWriter) Flush() {
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (cw *cacheWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
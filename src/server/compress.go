@@ -0,0 +1,210 @@
+package server
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Content codings, in order of preference
+const (
+	encodingBrotli = "br"
+	encodingGzip   = "gzip"
+)
+
+const brotliLevel = 5 // Fast enough for streamed exports, close to gzip -9 in size
+
+// compressibleTypes are the content types worth compressing, as prefixes
+var compressibleTypes = []string{
+	"text/",
+	"application/json",
+	"application/geo+json",
+	"application/x-ndjson",
+	"application/gpx+xml",
+	"application/vnd.google-earth.kml+xml",
+	"application/javascript",
+	"application/xml",
+	"image/svg+xml",
+	tileContentTypeMVT,
+}
+
+// encoder is the interface shared by the gzip and brotli writers
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(io.Writer)
+}
+
+var encoderPools = map[string]*sync.Pool{
+	encodingBrotli: {New: func() interface{} { return brotli.NewWriterLevel(io.Discard, brotliLevel) }},
+	encodingGzip:   {New: func() interface{} { return gzip.NewWriter(io.Discard) }},
+}
+
+// compress encodes responses of compressible types with brotli or gzip,
+// whichever the client prefers, while the cache.compression setting is on
+func compress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !compressionEnabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{
+			ResponseWriter: w,
+			encoding:       negotiateEncoding(r.Header.Get("Accept-Encoding")),
+			head:           r.Method == http.MethodHead,
+		}
+		defer cw.Close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// negotiateEncoding picks the content coding for an Accept-Encoding header,
+// or "" for none. Ties go to brotli.
+func negotiateEncoding(header string) string {
+	qualities := map[string]float64{}
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				q = 0
+			}
+		}
+		qualities[coding] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, coding := range []string{encodingBrotli, encodingGzip} {
+		q, ok := qualities[coding]
+		if !ok {
+			q = qualities["*"]
+		}
+		if q > bestQ {
+			best, bestQ = coding, q
+		}
+	}
+	return best
+}
+
+// compressible reports whether a content type is worth compressing
+func compressible(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	for _, prefix := range compressibleTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// encodingETag tags a representation compressed with encoding, so it is
+// told apart from the uncompressed one
+func encodingETag(etag, encoding string) string {
+	if !strings.HasSuffix(etag, `"`) {
+		return etag
+	}
+	return strings.TrimSuffix(etag, `"`) + "-" + encoding + `"`
+}
+
+// stripEncodingTag removes the suffix encodingETag adds
+func stripEncodingTag(etag string) string {
+	for _, encoding := range []string{encodingBrotli, encodingGzip} {
+		if trimmed, ok := strings.CutSuffix(etag, "-"+encoding+`"`); ok {
+			return trimmed + `"`
+		}
+	}
+	return etag
+}
+
+// compressWriter decides when the response header is written whether to
+// compress the body, based on its status and content type
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    string // Negotiated coding; "" when the client accepts none
+	head        bool
+	enc         encoder
+	wroteHeader bool
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.wroteHeader || status < http.StatusOK {
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	cw.wroteHeader = true
+
+	h := cw.Header()
+	switch {
+	case status == http.StatusNotModified:
+		// Name the representation the client would have received
+		h.Add("Vary", "Accept-Encoding")
+		if cw.encoding != "" && h.Get("ETag") != "" {
+			h.Set("ETag", encodingETag(h.Get("ETag"), cw.encoding))
+		}
+	case status == http.StatusNoContent, status == http.StatusPartialContent:
+	case h.Get("Content-Encoding") != "", !compressible(h.Get("Content-Type")):
+	default:
+		h.Add("Vary", "Accept-Encoding")
+		if cw.encoding == "" {
+			break
+		}
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		if etag := h.Get("ETag"); etag != "" {
+			h.Set("ETag", encodingETag(etag, cw.encoding))
+		}
+		if !cw.head {
+			cw.enc = encoderPools[cw.encoding].Get().(encoder)
+			cw.enc.Reset(cw.ResponseWriter)
+		}
+	}
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		if cw.Header().Get("Content-Type") == "" {
+			cw.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.enc != nil {
+		return cw.enc.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+func (cw *compressWriter) Flush() {
+	if cw.enc != nil {
+		cw.enc.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close finishes the compressed stream and returns the encoder to its pool
+func (cw *compressWriter) Close() {
+	if cw.enc == nil {
+		return
+	}
+	cw.enc.Close()
+	cw.enc.Reset(io.Discard)
+	encoderPools[cw.encoding].Put(cw.enc)
+	cw.enc = nil
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
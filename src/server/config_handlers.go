@@ -20,7 +20,7 @@ func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
 		"Settings": settings,
 	}
 
-	s.renderTemplate(w, "base.html", data)
+	s.renderTemplate(w, "config.html", data)
 }
 
 // handleConfigUpdate updates a single setting
@@ -48,8 +48,14 @@ func (s *Server) handleConfigUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	value, err := normalizeSetting(req.Key, req.Value)
+	if err != nil {
+		s.respondFieldError(w, http.StatusBadRequest, "INVALID_SETTING", err.Error(), req.Key)
+		return
+	}
+
 	// Update setting
-	err = database.SetSetting(req.Key, req.Value, req.Type, existing.Category, existing.Description)
+	err = database.SetSetting(req.Key, value, req.Type, existing.Category, existing.Description)
 	if err != nil {
 		s.respondError(w, http.StatusInternalServerError, "UPDATE_FAILED", err.Error())
 		return
@@ -129,7 +129,7 @@ func (s *Server) handleOpenAPISpec(w http.ResponseWriter, r *http.Request) {
 						{
 							"name":        "limit",
 							"in":          "query",
-							"description": "Results per page (default: 50, max: 1000)",
+							"description": "Results per page (default: 50, max: features.search_max_results, 1000 by default)",
 							"schema":      map[string]string{"type": "integer"},
 						},
 						{
@@ -255,13 +255,3 @@ func (s *Server) handleGraphQLPlayground(w http.ResponseWriter, r *http.Request)
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	t.Execute(w, nil)
 }
-
-// handleGraphQL handles GraphQL queries
-func (s *Server) handleGraphQL(w http.ResponseWriter, r *http.Request) {
-	// For now, return a simple message
-	// Full GraphQL implementation would go here
-	s.respondJSON(w, http.StatusOK, map[string]string{
-		"message":    "GraphQL endpoint - Full implementation coming soon",
-		"playground": "/graphql",
-	})
-}
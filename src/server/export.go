@@ -0,0 +1,376 @@
+package server
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/apimgr/airports/src/airports"
+)
+
+// exportFormat describes one downloadable representation of the airport data
+type exportFormat struct {
+	ext         string
+	contentType string
+	aliases     []string // Other media types accepted for this format
+	write       func(w io.Writer, list []*airports.Airport) error
+}
+
+// exportFormats lists the export formats in order of preference
+var exportFormats = []exportFormat{
+	{"json", "application/json", nil, writeJSONExport},
+	{"csv", "text/csv", []string{"application/csv"}, writeCSVExport},
+	{"geojson", "application/geo+json", []string{"application/vnd.geo+json"}, writeGeoJSONExport},
+	{"ndjson", "application/x-ndjson", []string{"application/ndjson", "application/jsonl"}, writeNDJSONExport},
+	{"kml", "application/vnd.google-earth.kml+xml", nil, writeKMLExport},
+	{"gpx", "application/gpx+xml", nil, writeGPXExport},
+}
+
+// exportFormatByExt returns the export format for a file extension
+func exportFormatByExt(ext string) (exportFormat, bool) {
+	for _, f := range exportFormats {
+		if f.ext == strings.ToLower(ext) {
+			return f, true
+		}
+	}
+	return exportFormat{}, false
+}
+
+// negotiateExportFormat picks the export format for an Accept header,
+// honouring quality values. An empty header or */* selects JSON.
+func negotiateExportFormat(accept string) (exportFormat, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return exportFormats[0], true
+	}
+
+	type candidate struct {
+		format exportFormat
+		q      float64
+	}
+	var candidates []candidate
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if qStr, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(qStr, 64); err != nil {
+				continue
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		for _, f := range exportFormats {
+			if f.matches(mediaType) {
+				candidates = append(candidates, candidate{f, q})
+				break
+			}
+		}
+	}
+
+	if len(candidates) == 0 {
+		return exportFormat{}, false
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+	return candidates[0].format, true
+}
+
+// matches reports whether a media type from an Accept header selects this format
+func (f exportFormat) matches(mediaType string) bool {
+	if mediaType == "*/*" || mediaType == f.contentType {
+		return true
+	}
+	for _, alias := range f.aliases {
+		if mediaType == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// handleExport streams the airports matching the list filters in the given format
+func (s *Server) handleExport(ext string) http.HandlerFunc {
+	format, ok := exportFormatByExt(ext)
+	if !ok {
+		panic("unknown export format: " + ext)
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.streamExport(w, r, format)
+	}
+}
+
+// handleExportNegotiated streams an export in the format chosen by the
+// format parameter or the Accept header
+func (s *Server) handleExportNegotiated(w http.ResponseWriter, r *http.Request) {
+	if ext := r.URL.Query().Get("format"); ext != "" {
+		format, ok := exportFormatByExt(ext)
+		if !ok {
+			s.respondFieldError(w, http.StatusBadRequest, "INVALID_PARAM",
+				"Invalid format (must be json, csv, geojson, ndjson, kml or gpx)", "format")
+			return
+		}
+		s.streamExport(w, r, format)
+		return
+	}
+
+	format, ok := negotiateExportFormat(r.Header.Get("Accept"))
+	if !ok {
+		s.respondError(w, http.StatusNotAcceptable, "NOT_ACCEPTABLE",
+			"Supported types: application/json, text/csv, application/geo+json, application/x-ndjson, application/vnd.google-earth.kml+xml, application/gpx+xml")
+		return
+	}
+	s.streamExport(w, r, format)
+}
+
+// streamExport writes every airport matching the request's list parameters
+func (s *Server) streamExport(w http.ResponseWriter, r *http.Request, format exportFormat) {
+	opts, err := parseListOptions(r.URL.Query())
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "INVALID_PARAM", err.Error())
+		return
+	}
+
+	list, err := s.airports.ListAll(opts)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "INVALID_PARAM", listError(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", format.contentType)
+	w.Header().Set("Content-Disposition", "attachment; filename=airports."+format.ext)
+	addVary(w.Header(), "Accept")
+
+	// Airports are encoded one at a time as they are written, so large
+	// exports are never buffered whole. Once the body has started an error
+	// can no longer be reported, so the connection is aborted instead of
+	// ending a truncated document cleanly.
+	if err := format.write(w, list); err != nil {
+		log.Printf("Export of %d airports as %s failed: %v", len(list), format.ext, err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// exportColumns are the CSV columns, matching the Airport JSON fields.
+// Runways and frequencies are only in the JSON formats.
+var exportColumns = []string{"icao", "iata", "name", "city", "state", "country", "elevation", "lat", "lon", "tz",
+	"type", "faa", "local_code", "scheduled_service"}
+
+// writeJSONExport writes airports as an object keyed by ICAO, like airports.json
+func writeJSONExport(w io.Writer, list []*airports.Airport) error {
+	if _, err := io.WriteString(w, "{"); err != nil {
+		return err
+	}
+	for i, apt := range list {
+		key, _ := json.Marshal(apt.ICAO)
+		value, err := json.Marshal(apt)
+		if err != nil {
+			return err
+		}
+		sep := ","
+		if i == 0 {
+			sep = ""
+		}
+		if _, err := fmt.Fprintf(w, "%s%s:%s", sep, key, value); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "}\n")
+	return err
+}
+
+// writeCSVExport writes airports as CSV with a header row
+func writeCSVExport(w io.Writer, list []*airports.Airport) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(exportColumns); err != nil {
+		return err
+	}
+	for _, apt := range list {
+		scheduled := ""
+		if apt.ScheduledService != nil {
+			scheduled = strconv.FormatBool(*apt.ScheduledService)
+		}
+		record := []string{
+			apt.ICAO,
+			apt.IATA,
+			apt.Name,
+			apt.City,
+			apt.State,
+			apt.Country,
+			strconv.Itoa(apt.Elevation),
+			strconv.FormatFloat(apt.Lat, 'f', -1, 64),
+			strconv.FormatFloat(apt.Lon, 'f', -1, 64),
+			apt.Tz,
+			apt.Type,
+			apt.FAA,
+			apt.LocalCode,
+			scheduled,
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeGeoJSONExport writes airports as a GeoJSON FeatureCollection
+func writeGeoJSONExport(w io.Writer, list []*airports.Airport) error {
+	if _, err := io.WriteString(w, `{"type":"FeatureCollection","features":[`); err != nil {
+		return err
+	}
+	for i, apt := range list {
+		if i > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		feature, err := json.Marshal(airportFeature(apt, apt))
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(feature); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "]}\n")
+	return err
+}
+
+// writeNDJSONExport writes one airport JSON object per line
+func writeNDJSONExport(w io.Writer, list []*airports.Airport) error {
+	enc := json.NewEncoder(w)
+	for _, apt := range list {
+		if err := enc.Encode(apt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// kmlPlacemark is a KML Placemark for one airport
+type kmlPlacemark struct {
+	XMLName     xml.Name  `xml:"Placemark"`
+	ID          string    `xml:"id,attr"` // An XML ID, which cannot start with a digit like some codes
+	Name        string    `xml:"name"`
+	Description string    `xml:"description"`
+	Data        []kmlData `xml:"ExtendedData>Data"`
+	Coordinates string    `xml:"Point>coordinates"`
+}
+
+// kmlData is a named value in a Placemark's ExtendedData
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+// writeKMLExport writes airports as a KML document of Placemarks
+func writeKMLExport(w io.Writer, list []*airports.Airport) error {
+	header := xml.Header + `<kml xmlns="http://www.opengis.net/kml/2.2"><Document><name>Airports</name>` + "\n"
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	for _, apt := range list {
+		elevation, _ := airports.ConvertElevation(apt.Elevation, airports.UnitMetric)
+		placemark := kmlPlacemark{
+			ID:          "apt-" + apt.ICAO,
+			Name:        apt.Name,
+			Description: airportDescription(apt),
+			Data: []kmlData{
+				{"icao", apt.ICAO},
+				{"iata", apt.IATA},
+				{"city", apt.City},
+				{"state", apt.State},
+				{"country", apt.Country},
+				{"elevation", strconv.Itoa(apt.Elevation)},
+				{"tz", apt.Tz},
+				{"type", apt.Type},
+			},
+			Coordinates: fmt.Sprintf("%s,%s,%s",
+				strconv.FormatFloat(apt.Lon, 'f', -1, 64),
+				strconv.FormatFloat(apt.Lat, 'f', -1, 64),
+				strconv.FormatFloat(elevation, 'f', 1, 64)),
+		}
+		if err := enc.Encode(placemark); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(w, "</Document></kml>\n")
+	return err
+}
+
+// gpxWaypoint is a GPX waypoint for one airport
+type gpxWaypoint struct {
+	XMLName   xml.Name `xml:"wpt"`
+	Lat       float64  `xml:"lat,attr"`
+	Lon       float64  `xml:"lon,attr"`
+	Elevation float64  `xml:"ele"`
+	Name      string   `xml:"name"`
+	Desc      string   `xml:"desc"`
+	Type      string   `xml:"type"`
+}
+
+// writeGPXExport writes airports as GPX 1.1 waypoints
+func writeGPXExport(w io.Writer, list []*airports.Airport) error {
+	header := xml.Header + `<gpx version="1.1" creator="airports" xmlns="http://www.topografix.com/GPX/1/1">` + "\n"
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	for _, apt := range list {
+		// GPX elevations are in meters
+		elevation, _ := airports.ConvertElevation(apt.Elevation, airports.UnitMetric)
+		elevation = math.Round(elevation*10) / 10
+		waypoint := gpxWaypoint{
+			Lat:       apt.Lat,
+			Lon:       apt.Lon,
+			Elevation: elevation,
+			Name:      apt.ICAO,
+			Desc:      airportDescription(apt),
+			Type:      "Airport",
+		}
+		if err := enc.Encode(waypoint); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(w, "</gpx>\n")
+	return err
+}
+
+// airportDescription summarises an airport for KML and GPX descriptions
+func airportDescription(apt *airports.Airport) string {
+	parts := []string{apt.Name}
+	if apt.IATA != "" {
+		parts[0] += " (" + apt.IATA + ")"
+	}
+	for _, part := range []string{apt.City, apt.State, apt.Country} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// fieldSet holds the JSON fields selected with the fields query parameter.
+// A nil fieldSet selects every field.
+type fieldSet map[string]bool
+
+// parseFields reads a comma-separated fields parameter and validates each
+// name against the JSON fields of sample's type
+func parseFields(query url.Values, sample interface{}) (fieldSet, error) {
+	param := strings.TrimSpace(query.Get("fields"))
+	if param == "" {
+		return nil, nil
+	}
+
+	allowed := jsonFieldNames(reflect.TypeOf(sample))
+	fields := fieldSet{}
+	for _, name := range strings.Split(param, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !allowed[name] {
+			names := make([]string, 0, len(allowed))
+			for n := range allowed {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("unknown field %q (valid fields: %s)", name, strings.Join(names, ", "))
+		}
+		fields[name] = true
+	}
+
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return fields, nil
+}
+
+// parseFieldsParam parses the fields parameter for a response of sample's
+// type, writing a validation error and returning false if it is invalid
+func (s *Server) parseFieldsParam(w http.ResponseWriter, r *http.Request, sample interface{}) (fieldSet, bool) {
+	fields, err := parseFields(r.URL.Query(), sample)
+	if err != nil {
+		s.respondFieldError(w, http.StatusBadRequest, "INVALID_PARAM", err.Error(), "fields")
+		return nil, false
+	}
+	return fields, true
+}
+
+// apply returns v reduced to the selected fields. v may be a struct, a
+// pointer to one, or a slice of either.
+func (f fieldSet) apply(v interface{}) interface{} {
+	if f == nil {
+		return v
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice {
+		var items []map[string]json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil {
+			return v
+		}
+		for _, item := range items {
+			f.filter(item)
+		}
+		if items == nil {
+			return []map[string]json.RawMessage{}
+		}
+		return items
+	}
+
+	var item map[string]json.RawMessage
+	if err := json.Unmarshal(data, &item); err != nil {
+		return v
+	}
+	f.filter(item)
+	return item
+}
+
+// filter removes unselected fields from a decoded object
+func (f fieldSet) filter(item map[string]json.RawMessage) {
+	for name := range item {
+		if !f[name] {
+			delete(item, name)
+		}
+	}
+}
+
+// jsonFieldNames returns the JSON field names of a struct type (or pointer
+// or slice of one), including fields of embedded structs
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+
+	names := make(map[string]bool)
+	if t.Kind() != reflect.Struct {
+		return names
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if field.Anonymous && name == "" {
+			for embedded := range jsonFieldNames(field.Type) {
+				names[embedded] = true
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names[name] = true
+	}
+	return names
+}
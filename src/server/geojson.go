@@ -0,0 +1,119 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/apimgr/airports/src/airports"
+)
+
+// geoJSONFeature is a GeoJSON Point feature
+type geoJSONFeature struct {
+	Type       string          `json:"type"`
+	ID         string          `json:"id,omitempty"`
+	Geometry   geoJSONGeometry `json:"geometry"`
+	Properties interface{}     `json:"properties"`
+}
+
+// geoJSONGeometry is a GeoJSON geometry object
+type geoJSONGeometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+// airportFeature builds a Point feature for an airport with the given properties
+func airportFeature(apt *airports.Airport, properties interface{}) geoJSONFeature {
+	return geoJSONFeature{
+		Type: "Feature",
+		ID:   apt.ICAO,
+		Geometry: geoJSONGeometry{
+			Type:        "Point",
+			Coordinates: []float64{apt.Lon, apt.Lat},
+		},
+		Properties: properties,
+	}
+}
+
+// geoJSONFeatureCollection is a GeoJSON FeatureCollection. Response
+// metadata such as counts and pagination goes in the metadata foreign member.
+type geoJSONFeatureCollection struct {
+	Type     string                 `json:"type"`
+	Features []geoJSONFeature       `json:"features"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// airportFeatures builds Point features for a list of results. Each result's
+// JSON fields, reduced by fields, become the feature properties.
+func airportFeatures[T any](items []T, airport func(T) *airports.Airport, fields fieldSet) []geoJSONFeature {
+	features := make([]geoJSONFeature, len(items))
+	for i, item := range items {
+		features[i] = airportFeature(airport(item), fields.apply(item))
+	}
+	return features
+}
+
+// withDistanceAirport returns the airport of a distance result
+func withDistanceAirport(a airports.AirportWithDistance) *airports.Airport {
+	return &a.Airport
+}
+
+// errInvalidFormat is returned for a format parameter other than json or geojson
+var errInvalidFormat = errors.New("invalid format (must be json or geojson)")
+
+// wantsGeoJSON reports whether the client asked for GeoJSON with format=geojson
+// or an Accept header preferring application/geo+json. An invalid format
+// value is returned as an error.
+func wantsGeoJSON(r *http.Request) (bool, error) {
+	switch format := strings.ToLower(r.URL.Query().Get("format")); format {
+	case "geojson":
+		return true, nil
+	case "json":
+		return false, nil
+	case "":
+		accepted, ok := negotiateExportFormat(r.Header.Get("Accept"))
+		return ok && accepted.ext == "geojson", nil
+	default:
+		return false, errInvalidFormat
+	}
+}
+
+// parseFormatParam reports whether to respond with GeoJSON, writing a
+// validation error and returning false if the format parameter is invalid.
+// The response depends on the Accept header whichever format is chosen, so
+// every negotiated response says so.
+func (s *Server) parseFormatParam(w http.ResponseWriter, r *http.Request) (geo bool, ok bool) {
+	addVary(w.Header(), "Accept")
+	geo, err := wantsGeoJSON(r)
+	if err != nil {
+		s.respondFieldError(w, http.StatusBadRequest, "INVALID_PARAM", err.Error(), "format")
+		return false, false
+	}
+	return geo, true
+}
+
+// addVary adds a request header to the Vary list unless it is already named,
+// keeping the values other middleware has added
+func addVary(h http.Header, name string) {
+	for _, value := range h.Values("Vary") {
+		for _, field := range strings.Split(value, ",") {
+			if field = strings.TrimSpace(field); field == "*" || strings.EqualFold(field, name) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", name)
+}
+
+// respondGeoJSON writes a FeatureCollection without the standard envelope
+func (s *Server) respondGeoJSON(w http.ResponseWriter, status int, features []geoJSONFeature, metadata map[string]interface{}) {
+	w.Header().Set("Content-Type", "application/geo+json")
+	w.WriteHeader(status)
+
+	json.NewEncoder(w).Encode(geoJSONFeatureCollection{
+		Type:     "FeatureCollection",
+		Features: features,
+		Metadata: metadata,
+	})
+}
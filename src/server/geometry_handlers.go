@@ -0,0 +1,259 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/apimgr/airports/src/airports"
+)
+
+// Geometry request limits. Detailed country outlines run to a few megabytes
+// and tens of thousands of vertices; matching cost grows with both the
+// vertex and route position counts.
+const (
+	maxGeometryBodyBytes = 16 << 20 // Polygon and route request body
+	polygonMaxVertices   = 100000   // Vertices across all polygons and rings
+	routeMaxPositions    = 2000     // LineString positions
+)
+
+// geoJSONObject is any GeoJSON object read from a request body
+type geoJSONObject struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Geometry    *geoJSONObject  `json:"geometry"`
+	Geometries  []geoJSONObject `json:"geometries"`
+	Features    []geoJSONObject `json:"features"`
+}
+
+// geometries flattens Features, FeatureCollections and GeometryCollections
+// into their geometries
+func (o geoJSONObject) geometries() []geoJSONObject {
+	switch o.Type {
+	case "Feature":
+		if o.Geometry == nil {
+			return nil
+		}
+		return o.Geometry.geometries()
+	case "FeatureCollection":
+		var all []geoJSONObject
+		for _, f := range o.Features {
+			all = append(all, f.geometries()...)
+		}
+		return all
+	case "GeometryCollection":
+		var all []geoJSONObject
+		for _, g := range o.Geometries {
+			all = append(all, g.geometries()...)
+		}
+		return all
+	default:
+		return []geoJSONObject{o}
+	}
+}
+
+// geoJSONPositions converts [lon, lat] positions to coordinates
+func geoJSONPositions(positions [][]float64) ([]airports.Coordinates, error) {
+	coords := make([]airports.Coordinates, len(positions))
+	for i, p := range positions {
+		if len(p) < 2 {
+			return nil, fmt.Errorf("position must have a longitude and latitude")
+		}
+		coords[i] = airports.Coordinates{Lat: p[1], Lon: p[0]}
+	}
+	return coords, nil
+}
+
+// geoJSONPolygon converts Polygon coordinates
+func geoJSONPolygon(rings [][][]float64) (airports.Polygon, error) {
+	polygon := make(airports.Polygon, len(rings))
+	for i, ring := range rings {
+		coords, err := geoJSONPositions(ring)
+		if err != nil {
+			return nil, err
+		}
+		polygon[i] = coords
+	}
+	return polygon, nil
+}
+
+// parsePolygons reads the Polygons and MultiPolygons in a GeoJSON body
+func parsePolygons(body geoJSONObject) ([]airports.Polygon, error) {
+	var polygons []airports.Polygon
+	vertices := 0
+	countVertices := func(rings [][][]float64) error {
+		for _, ring := range rings {
+			vertices += len(ring)
+		}
+		if vertices > polygonMaxVertices {
+			return fmt.Errorf("too many polygon vertices (max %d)", polygonMaxVertices)
+		}
+		return nil
+	}
+
+	for _, g := range body.geometries() {
+		switch g.Type {
+		case "Polygon":
+			var rings [][][]float64
+			if err := json.Unmarshal(g.Coordinates, &rings); err != nil {
+				return nil, fmt.Errorf("invalid Polygon coordinates")
+			}
+			if err := countVertices(rings); err != nil {
+				return nil, err
+			}
+			polygon, err := geoJSONPolygon(rings)
+			if err != nil {
+				return nil, err
+			}
+			polygons = append(polygons, polygon)
+		case "MultiPolygon":
+			var multi [][][][]float64
+			if err := json.Unmarshal(g.Coordinates, &multi); err != nil {
+				return nil, fmt.Errorf("invalid MultiPolygon coordinates")
+			}
+			for _, rings := range multi {
+				if err := countVertices(rings); err != nil {
+					return nil, err
+				}
+				polygon, err := geoJSONPolygon(rings)
+				if err != nil {
+					return nil, err
+				}
+				polygons = append(polygons, polygon)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported geometry %q (must be Polygon or MultiPolygon)", g.Type)
+		}
+	}
+	if len(polygons) == 0 {
+		return nil, fmt.Errorf("body must contain a Polygon or MultiPolygon")
+	}
+	return polygons, nil
+}
+
+// parseRoute reads the LineString in a GeoJSON body
+func parseRoute(body geoJSONObject) ([]airports.Coordinates, error) {
+	geometries := body.geometries()
+	if len(geometries) != 1 || geometries[0].Type != "LineString" {
+		return nil, fmt.Errorf("body must contain exactly one LineString")
+	}
+
+	var positions [][]float64
+	if err := json.Unmarshal(geometries[0].Coordinates, &positions); err != nil {
+		return nil, fmt.Errorf("invalid LineString coordinates")
+	}
+	if len(positions) > routeMaxPositions {
+		return nil, fmt.Errorf("too many route positions (max %d)", routeMaxPositions)
+	}
+	return geoJSONPositions(positions)
+}
+
+// handleWithinPolygon finds airports inside the GeoJSON Polygon or
+// MultiPolygon in the request body
+func (s *Server) handleWithinPolygon(w http.ResponseWriter, r *http.Request) {
+	filters, err := parseFilters(r.URL.Query())
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "INVALID_PARAM", err.Error())
+		return
+	}
+	fields, ok := s.parseFieldsParam(w, r, airports.Airport{})
+	if !ok {
+		return
+	}
+	geo, ok := s.parseFormatParam(w, r)
+	if !ok {
+		return
+	}
+
+	var body geoJSONObject
+	if !s.decodeJSONBody(w, r, maxGeometryBodyBytes, &body) {
+		return
+	}
+	polygons, err := parsePolygons(body)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "INVALID_GEOMETRY", err.Error())
+		return
+	}
+
+	results, err := s.airports.WithinPolygon(polygons, filters)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "INVALID_GEOMETRY", err.Error())
+		return
+	}
+
+	if geo {
+		features := airportFeatures(results, func(apt *airports.Airport) *airports.Airport { return apt }, fields)
+		s.respondGeoJSON(w, http.StatusOK, features, map[string]interface{}{
+			"count": len(results),
+		})
+		return
+	}
+
+	s.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"airports": fields.apply(results),
+		"count":    len(results),
+	})
+}
+
+// handleAlongRoute finds airports within a corridor around the GeoJSON
+// LineString in the request body
+func (s *Server) handleAlongRoute(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	units := requestUnits(query.Get("units"))
+
+	// The corridor is given in the unit system's distance unit
+	corridor, displayCorridor, err := parseRadius(query.Get("corridor"), units, 50, nearbyMaxRadius())
+	if err != nil || math.IsNaN(corridor) || corridor < 0 {
+		s.respondFieldError(w, http.StatusBadRequest, "INVALID_PARAM", "Invalid corridor (must be a non-negative number)", "corridor")
+		return
+	}
+
+	filters, err := parseFilters(query)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "INVALID_PARAM", err.Error())
+		return
+	}
+	fields, ok := s.parseFieldsParam(w, r, airports.AirportAlongRoute{})
+	if !ok {
+		return
+	}
+	geo, ok := s.parseFormatParam(w, r)
+	if !ok {
+		return
+	}
+
+	var body geoJSONObject
+	if !s.decodeJSONBody(w, r, maxGeometryBodyBytes, &body) {
+		return
+	}
+	route, err := parseRoute(body)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "INVALID_GEOMETRY", err.Error())
+		return
+	}
+
+	results, err := s.airports.AlongRoute(route, corridor, filters, units)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "INVALID_GEOMETRY", err.Error())
+		return
+	}
+
+	_, corridorUnit := airports.ConvertDistance(corridor, units)
+	data := map[string]interface{}{
+		"corridor":      displayCorridor,
+		"corridor_unit": corridorUnit,
+		"units":         units,
+		"count":         len(results),
+	}
+
+	if geo {
+		features := airportFeatures(results, func(a airports.AirportAlongRoute) *airports.Airport { return &a.Airport }, fields)
+		s.respondGeoJSON(w, http.StatusOK, features, data)
+		return
+	}
+
+	data["airports"] = fields.apply(results)
+	s.respondJSON(w, http.StatusOK, data)
+}
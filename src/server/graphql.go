@@ -0,0 +1,490 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/apimgr/airports/src/airports"
+	"github.com/apimgr/airports/src/geoip"
+	"github.com/graphql-go/graphql"
+)
+
+const clientIPKey contextKey = "client_ip"
+
+// graphQLRequest is the standard GraphQL-over-HTTP request body
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// handleGraphQL executes GraphQL queries against the airport and GeoIP services
+func (s *Server) handleGraphQL(w http.ResponseWriter, r *http.Request) {
+	if s.graphqlSchema == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "GRAPHQL_UNAVAILABLE", "GraphQL schema failed to load")
+		return
+	}
+
+	var req graphQLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondError(w, http.StatusBadRequest, "INVALID_JSON", "Invalid JSON")
+		return
+	}
+
+	if strings.TrimSpace(req.Query) == "" {
+		s.respondError(w, http.StatusBadRequest, "INVALID_QUERY", "Query is required")
+		return
+	}
+
+	// Make the caller's IP, as resolved by realIP, available to geoip resolvers
+	ctx := context.WithValue(r.Context(), clientIPKey, remoteHost(r.RemoteAddr))
+
+	result := graphql.Do(graphql.Params{
+		Schema:         *s.graphqlSchema,
+		RequestString:  req.Query,
+		OperationName:  req.OperationName,
+		VariableValues: req.Variables,
+		Context:        ctx,
+	})
+
+	// GraphQL responses use the spec format ({data, errors}) rather than the
+	// REST envelope so standard clients and the playground can consume them
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// buildGraphQLSchema creates the GraphQL schema over the airport and GeoIP services
+func (s *Server) buildGraphQLSchema() (*graphql.Schema, error) {
+	coordinatesType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Coordinates",
+		Description: "Geographic position in decimal degrees",
+		Fields: graphql.Fields{
+			"lat": {Type: graphql.NewNonNull(graphql.Float), Resolve: resolveAirport(func(a *airports.Airport) interface{} { return a.Lat })},
+			"lon": {Type: graphql.NewNonNull(graphql.Float), Resolve: resolveAirport(func(a *airports.Airport) interface{} { return a.Lon })},
+		},
+	})
+
+	runwayType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Runway",
+		Description: "A runway of an airport",
+		Fields: graphql.Fields{
+			"ident":    {Type: graphql.NewNonNull(graphql.String), Description: "Both ends, e.g. 04L/22R", Resolve: resolveRunway(func(r airports.Runway) interface{} { return r.Ident })},
+			"lengthFt": {Type: graphql.Int, Description: "Length in feet", Resolve: resolveRunway(func(r airports.Runway) interface{} { return r.LengthFt })},
+			"widthFt":  {Type: graphql.Int, Description: "Width in feet", Resolve: resolveRunway(func(r airports.Runway) interface{} { return r.WidthFt })},
+			"surface":  {Type: graphql.String, Description: "Surface code, e.g. ASP, CON, TURF", Resolve: resolveRunway(func(r airports.Runway) interface{} { return r.Surface })},
+			"heading":  {Type: graphql.Float, Description: "Degrees true at the low end", Resolve: resolveRunway(func(r airports.Runway) interface{} { return r.Heading })},
+			"lighted":  {Type: graphql.NewNonNull(graphql.Boolean), Resolve: resolveRunway(func(r airports.Runway) interface{} { return r.Lighted })},
+			"closed":   {Type: graphql.NewNonNull(graphql.Boolean), Resolve: resolveRunway(func(r airports.Runway) interface{} { return r.Closed })},
+		},
+	})
+
+	frequencyType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Frequency",
+		Description: "A radio frequency of an airport",
+		Fields: graphql.Fields{
+			"type":        {Type: graphql.NewNonNull(graphql.String), Description: "e.g. TWR, GND, ATIS"},
+			"description": {Type: graphql.String},
+			"mhz":         {Type: graphql.NewNonNull(graphql.Float)},
+		},
+	})
+
+	// NearbyAirport is an airport with its distance from the search point
+	nearbyFields := airportFields(coordinatesType, runwayType, frequencyType)
+	nearbyFields["distance"] = &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.Float),
+		Description: "Distance from the search point in distanceUnit",
+	}
+	nearbyFields["distanceUnit"] = &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.String),
+		Description: "Unit of distance (mi or km)",
+	}
+	nearbyAirportType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "NearbyAirport",
+		Description: "An airport with its distance from a search point",
+		Fields:      nearbyFields,
+	})
+
+	airportFieldsWithNearby := airportFields(coordinatesType, runwayType, frequencyType)
+	airportFieldsWithNearby["nearby"] = &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(nearbyAirportType))),
+		Description: "Other airports near this one, closest first",
+		Args: graphql.FieldConfigArgument{
+			"radius": {Type: graphql.Float, DefaultValue: nearbyDefaultRadius, Description: "Search radius in the units' distance unit (capped at features.nearby_max_radius)"},
+			"limit":  {Type: graphql.Int, DefaultValue: 20},
+			"units":  {Type: graphql.String, Description: "imperial, metric or nautical (default: server.default_units)"},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			apt := airportFromSource(p.Source)
+			if apt == nil {
+				return nil, nil
+			}
+			radius, limit, units := nearbyArgs(p.Args)
+			// Ask for one extra result since the airport itself is always closest
+			results := s.airports.GetNearbyWithDistance(apt.Lat, apt.Lon, radius, limit+1, units)
+			nearby := make([]airports.AirportWithDistance, 0, len(results))
+			for _, r := range results {
+				if r.ICAO == apt.ICAO {
+					continue
+				}
+				if len(nearby) == limit {
+					break
+				}
+				nearby = append(nearby, r)
+			}
+			return nearby, nil
+		},
+	}
+	airportType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Airport",
+		Description: "An airport from the global airport database",
+		Fields:      airportFieldsWithNearby,
+	})
+
+	countryType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "CountryCount",
+		Description: "Number of airports in a country",
+		Fields: graphql.Fields{
+			"code":  {Type: graphql.NewNonNull(graphql.String), Description: "ISO 3166-1 alpha-2 country code"},
+			"count": {Type: graphql.NewNonNull(graphql.Int)},
+		},
+	})
+
+	stateType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "StateCount",
+		Description: "Number of airports in a state or region",
+		Fields: graphql.Fields{
+			"name":  {Type: graphql.NewNonNull(graphql.String)},
+			"count": {Type: graphql.NewNonNull(graphql.Int)},
+		},
+	})
+
+	statsType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Stats",
+		Description: "Airport database statistics",
+		Fields: graphql.Fields{
+			"totalAirports": {Type: graphql.NewNonNull(graphql.Int), Resolve: resolveStat("total_airports")},
+			"countries":     {Type: graphql.NewNonNull(graphql.Int), Resolve: resolveStat("countries")},
+			"cities":        {Type: graphql.NewNonNull(graphql.Int), Resolve: resolveStat("cities")},
+			"withIata":      {Type: graphql.NewNonNull(graphql.Int), Resolve: resolveStat("with_iata")},
+		},
+	})
+
+	geoLocationType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "GeoLocation",
+		Description: "Geolocation information for an IP address",
+		Fields: graphql.Fields{
+			"ip":          {Type: graphql.NewNonNull(graphql.String)},
+			"country":     {Type: graphql.String, Description: "ISO country code"},
+			"countryName": {Type: graphql.String},
+			"region":      {Type: graphql.String, Description: "State/province code"},
+			"regionName":  {Type: graphql.String},
+			"city":        {Type: graphql.String},
+			"latitude":    {Type: graphql.Float},
+			"longitude":   {Type: graphql.Float},
+			"timeZone":    {Type: graphql.String},
+			"postalCode":  {Type: graphql.String},
+			"asn":         {Type: graphql.Int},
+			"asnOrg":      {Type: graphql.String},
+		},
+	})
+
+	geoIPNearbyType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "GeoIPNearby",
+		Description: "Airports near the location of an IP address",
+		Fields: graphql.Fields{
+			"location": {Type: graphql.NewNonNull(geoLocationType)},
+			"airports": {Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(nearbyAirportType)))},
+		},
+	})
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"airport": {
+				Type:        airportType,
+				Description: "Look up an airport by ICAO or IATA code",
+				Args: graphql.FieldConfigArgument{
+					"code": {Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					apt, err := s.airports.GetByCode(p.Args["code"].(string))
+					if err != nil {
+						// Unknown codes resolve to null rather than an error
+						return nil, nil
+					}
+					return apt, nil
+				},
+			},
+			"search": {
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(airportType))),
+				Description: "Search airports by name, city or code",
+				Args: graphql.FieldConfigArgument{
+					"query":  {Type: graphql.NewNonNull(graphql.String)},
+					"limit":  {Type: graphql.Int, DefaultValue: 50, Description: "Max results (capped at features.search_max_results)"},
+					"offset": {Type: graphql.Int, DefaultValue: 0},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					limit := p.Args["limit"].(int)
+					if limit <= 0 || limit > searchMaxResults() {
+						limit = min(50, searchMaxResults())
+					}
+					offset := p.Args["offset"].(int)
+					if offset < 0 {
+						offset = 0
+					}
+					results, _ := s.airports.Search(p.Args["query"].(string), limit, offset)
+					return results, nil
+				},
+			},
+			"nearby": {
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(nearbyAirportType))),
+				Description: "Find airports within a radius of coordinates, closest first",
+				Args: graphql.FieldConfigArgument{
+					"lat":    {Type: graphql.NewNonNull(graphql.Float)},
+					"lon":    {Type: graphql.NewNonNull(graphql.Float)},
+					"radius": {Type: graphql.Float, DefaultValue: nearbyDefaultRadius, Description: "Search radius in the units' distance unit (capped at features.nearby_max_radius)"},
+					"limit":  {Type: graphql.Int, DefaultValue: 20},
+					"units":  {Type: graphql.String, Description: "imperial, metric or nautical (default: server.default_units)"},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					radius, limit, units := nearbyArgs(p.Args)
+					return s.airports.GetNearbyWithDistance(p.Args["lat"].(float64), p.Args["lon"].(float64), radius, limit, units), nil
+				},
+			},
+			"bbox": {
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(airportType))),
+				Description: "Find airports within a bounding box",
+				Args: graphql.FieldConfigArgument{
+					"minLat": {Type: graphql.NewNonNull(graphql.Float)},
+					"maxLat": {Type: graphql.NewNonNull(graphql.Float)},
+					"minLon": {Type: graphql.NewNonNull(graphql.Float)},
+					"maxLon": {Type: graphql.NewNonNull(graphql.Float)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return s.airports.GetInBoundingBox(
+						p.Args["minLat"].(float64), p.Args["maxLat"].(float64),
+						p.Args["minLon"].(float64), p.Args["maxLon"].(float64),
+					), nil
+				},
+			},
+			"countries": {
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(countryType))),
+				Description: "All countries with airport counts",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return sortedCounts(s.airports.GetCountries(), "code"), nil
+				},
+			},
+			"states": {
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(stateType))),
+				Description: "States in a country with airport counts",
+				Args: graphql.FieldConfigArgument{
+					"country": {Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return sortedCounts(s.airports.GetStatesInCountry(p.Args["country"].(string)), "name"), nil
+				},
+			},
+			"stats": {
+				Type:        graphql.NewNonNull(statsType),
+				Description: "Airport database statistics",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return s.airports.Stats(), nil
+				},
+			},
+			"geoip": {
+				Type:        geoLocationType,
+				Description: "Geolocate an IP address (defaults to the caller's IP)",
+				Args: graphql.FieldConfigArgument{
+					"ip": {Type: graphql.String},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return s.graphqlLookupIP(p)
+				},
+			},
+			"geoipNearby": {
+				Type:        geoIPNearbyType,
+				Description: "Find airports near the location of an IP address (defaults to the caller's IP)",
+				Args: graphql.FieldConfigArgument{
+					"ip":     {Type: graphql.String},
+					"radius": {Type: graphql.Float, DefaultValue: geoIPNearbyDefaultRadius, Description: "Search radius in the units' distance unit (capped at features.nearby_max_radius)"},
+					"limit":  {Type: graphql.Int, DefaultValue: 10},
+					"units":  {Type: graphql.String, Description: "imperial, metric or nautical (default: server.default_units)"},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					location, err := s.graphqlLookupIP(p)
+					if err != nil {
+						return nil, err
+					}
+					radius, limit, units := nearbyArgs(p.Args)
+					return map[string]interface{}{
+						"location": location,
+						"airports": s.airports.GetNearbyWithDistance(location.Latitude, location.Longitude, radius, limit, units),
+					}, nil
+				},
+			},
+		},
+	})
+
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+	if err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+// graphqlLookupIP geolocates the ip argument, or the caller's IP if absent
+func (s *Server) graphqlLookupIP(p graphql.ResolveParams) (*geoip.GeoLocation, error) {
+	ipStr, _ := p.Args["ip"].(string)
+	if ipStr == "" {
+		ipStr, _ = p.Context.Value(clientIPKey).(string)
+	}
+	if ipStr == "" {
+		return nil, fmt.Errorf("could not determine client IP address")
+	}
+	return s.geoip.LookupString(ipStr)
+}
+
+// airportFields returns the fields shared by every airport-shaped GraphQL type
+func airportFields(coordinatesType, runwayType, frequencyType *graphql.Object) graphql.Fields {
+	return graphql.Fields{
+		"icao":      {Type: graphql.NewNonNull(graphql.String), Resolve: resolveAirport(func(a *airports.Airport) interface{} { return a.ICAO })},
+		"iata":      {Type: graphql.String, Resolve: resolveAirport(func(a *airports.Airport) interface{} { return a.IATA })},
+		"name":      {Type: graphql.NewNonNull(graphql.String), Resolve: resolveAirport(func(a *airports.Airport) interface{} { return a.Name })},
+		"city":      {Type: graphql.String, Resolve: resolveAirport(func(a *airports.Airport) interface{} { return a.City })},
+		"state":     {Type: graphql.String, Resolve: resolveAirport(func(a *airports.Airport) interface{} { return a.State })},
+		"country":   {Type: graphql.String, Resolve: resolveAirport(func(a *airports.Airport) interface{} { return a.Country })},
+		"elevation": {Type: graphql.Int, Description: "Elevation in feet", Resolve: resolveAirport(func(a *airports.Airport) interface{} { return a.Elevation })},
+		"lat":       {Type: graphql.NewNonNull(graphql.Float), Resolve: resolveAirport(func(a *airports.Airport) interface{} { return a.Lat })},
+		"lon":       {Type: graphql.NewNonNull(graphql.Float), Resolve: resolveAirport(func(a *airports.Airport) interface{} { return a.Lon })},
+		"tz":        {Type: graphql.String, Description: "IANA timezone", Resolve: resolveAirport(func(a *airports.Airport) interface{} { return a.Tz })},
+		"type":      {Type: graphql.String, Description: "large_airport, medium_airport, small_airport, heliport, seaplane_base, balloonport or closed", Resolve: resolveAirport(func(a *airports.Airport) interface{} { return a.Type })},
+		"faa":       {Type: graphql.String, Description: "FAA location identifier (US airports)", Resolve: resolveAirport(func(a *airports.Airport) interface{} { return a.FAA })},
+		"localCode": {Type: graphql.String, Description: "National airport code", Resolve: resolveAirport(func(a *airports.Airport) interface{} { return a.LocalCode })},
+		"scheduledService": {
+			Type:        graphql.Boolean,
+			Description: "Has scheduled airline service; null when unknown",
+			Resolve: resolveAirport(func(a *airports.Airport) interface{} {
+				if a.ScheduledService == nil {
+					return nil
+				}
+				return *a.ScheduledService
+			}),
+		},
+		"runways": {
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(runwayType))),
+			Resolve: resolveAirport(func(a *airports.Airport) interface{} {
+				if a.Runways == nil {
+					return []airports.Runway{}
+				}
+				return a.Runways
+			}),
+		},
+		"frequencies": {
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(frequencyType))),
+			Resolve: resolveAirport(func(a *airports.Airport) interface{} {
+				if a.Frequencies == nil {
+					return []airports.Frequency{}
+				}
+				return a.Frequencies
+			}),
+		},
+		"coordinates": {
+			Type: graphql.NewNonNull(coordinatesType),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return airportFromSource(p.Source), nil
+			},
+		},
+	}
+}
+
+// resolveAirport builds a resolver that reads a value from the source airport
+func resolveAirport(get func(a *airports.Airport) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		apt := airportFromSource(p.Source)
+		if apt == nil {
+			return nil, nil
+		}
+		return get(apt), nil
+	}
+}
+
+// resolveRunway builds a resolver that reads a value from the source runway
+func resolveRunway(get func(r airports.Runway) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		r, ok := p.Source.(airports.Runway)
+		if !ok {
+			return nil, nil
+		}
+		return get(r), nil
+	}
+}
+
+// airportFromSource unwraps the airport from any airport-shaped resolver source
+func airportFromSource(source interface{}) *airports.Airport {
+	switch v := source.(type) {
+	case *airports.Airport:
+		return v
+	case airports.Airport:
+		return &v
+	case airports.AirportWithDistance:
+		return &v.Airport
+	case *airports.AirportWithDistance:
+		return &v.Airport
+	case airports.SearchResult:
+		return &v.Airport
+	}
+	return nil
+}
+
+// resolveStat reads a value from the Stats() map
+func resolveStat(key string) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		stats, _ := p.Source.(map[string]interface{})
+		return stats[key], nil
+	}
+}
+
+// nearbyArgs extracts radius in km, limit and units from resolver args,
+// capped by the nearby radius and search result settings. The radius
+// argument is in the units' distance unit.
+func nearbyArgs(args map[string]interface{}) (float64, int, string) {
+	unitsParam, _ := args["units"].(string)
+	units := requestUnits(unitsParam)
+
+	radius, _ := args["radius"].(float64)
+	if radius <= 0 {
+		radius = nearbyDefaultRadius
+	}
+	radius = math.Min(airports.DistanceToKm(radius, units), nearbyMaxRadius())
+
+	limit, _ := args["limit"].(int)
+	if limit <= 0 {
+		limit = 20
+	}
+	limit = min(limit, searchMaxResults())
+
+	return radius, limit, units
+}
+
+// sortedCounts converts a name->count map into a list sorted by name
+func sortedCounts(counts map[string]int, nameKey string) []map[string]interface{} {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make([]map[string]interface{}, 0, len(names))
+	for _, name := range names {
+		result = append(result, map[string]interface{}{
+			nameKey: name,
+			"count": counts[name],
+		})
+	}
+	return result
+}
@@ -1,10 +1,12 @@
 package server
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 
@@ -19,9 +21,9 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	stats := s.airports.Stats()
 
 	health := map[string]interface{}{
-		"status":    "healthy",
-		"timestamp": "2024-01-01T12:00:00Z",
-		"version":   "dev",
+		"status":         "healthy",
+		"timestamp":      "2024-01-01T12:00:00Z",
+		"version":        "dev",
 		"uptime_seconds": 0,
 		"checks": map[string]interface{}{
 			"airports": map[string]interface{}{
@@ -39,67 +41,123 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 
 // handleGetAirports returns paginated list of airports
 func (s *Server) handleGetAirports(w http.ResponseWriter, r *http.Request) {
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
-
-	if limit <= 0 || limit > 1000 {
-		limit = 50
-	}
+	s.respondAirportList(w, r, airports.Filters{})
+}
 
-	airports := s.airports.GetAll(limit, offset)
-	stats := s.airports.Stats()
+// handleGetCountryAirports returns paginated airports in a country
+func (s *Server) handleGetCountryAirports(w http.ResponseWriter, r *http.Request) {
+	s.respondAirportList(w, r, airports.Filters{
+		Country: chi.URLParam(r, "country"),
+	})
+}
 
-	s.respondJSON(w, http.StatusOK, map[string]interface{}{
-		"airports": airports,
-		"total":    stats["total_airports"],
-		"limit":    limit,
-		"offset":   offset,
+// handleGetStateAirports returns paginated airports in a state of a country
+func (s *Server) handleGetStateAirports(w http.ResponseWriter, r *http.Request) {
+	s.respondAirportList(w, r, airports.Filters{
+		Country: chi.URLParam(r, "country"),
+		State:   chi.URLParam(r, "state"),
 	})
 }
 
-// handleGetAirportsJSON returns the full airport database as JSON
-func (s *Server) handleGetAirportsJSON(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Content-Disposition", "attachment; filename=airports.json")
+// respondAirportList writes one page of airports matching the query
+// parameters. Path filters override the equivalent query parameters.
+func (s *Server) respondAirportList(w http.ResponseWriter, r *http.Request, pathFilters airports.Filters) {
+	opts, err := parseListOptions(r.URL.Query())
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "INVALID_PARAM", err.Error())
+		return
+	}
+	if pathFilters.Country != "" {
+		opts.Filters.Country = pathFilters.Country
+	}
+	if pathFilters.State != "" {
+		opts.Filters.State = pathFilters.State
+	}
+
+	fields, ok := s.parseFieldsParam(w, r, airports.Airport{})
+	if !ok {
+		return
+	}
+
+	list, info, err := s.airports.List(opts)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "INVALID_PARAM", listError(err))
+		return
+	}
 
-	data := s.airports.GetRawData()
-	json.NewEncoder(w).Encode(data)
+	data := map[string]interface{}{
+		"airports": fields.apply(list),
+	}
+	if opts.Query != "" {
+		data["query"] = opts.Query
+	}
+	addPagination(w, r, data, opts.Page, info)
+
+	s.respondJSON(w, http.StatusOK, data)
 }
 
 // handleGetAirportByCode returns a single airport by code
 func (s *Server) handleGetAirportByCode(w http.ResponseWriter, r *http.Request) {
 	code := chi.URLParam(r, "code")
 
+	fields, ok := s.parseFieldsParam(w, r, airports.Airport{})
+	if !ok {
+		return
+	}
+
 	airport, err := s.airports.GetByCode(code)
 	if err != nil {
 		s.respondError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("Airport not found: %s", code))
 		return
 	}
 
-	s.respondJSON(w, http.StatusOK, airport)
+	s.respondJSON(w, http.StatusOK, fields.apply(airport))
 }
 
 // handleSearchAirports searches for airports
 func (s *Server) handleSearchAirports(w http.ResponseWriter, r *http.Request) {
-	query := r.URL.Query().Get("q")
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	opts, err := parseListOptions(r.URL.Query())
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "INVALID_PARAM", err.Error())
+		return
+	}
 
-	if limit <= 0 || limit > 1000 {
-		limit = 50
+	fields, ok := s.parseFieldsParam(w, r, airports.SearchResult{})
+	if !ok {
+		return
+	}
+	geo, ok := s.parseFormatParam(w, r)
+	if !ok {
+		return
 	}
 
-	airports := s.airports.Search(query, limit, offset)
+	results, info, err := s.airports.SearchPage(opts)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "INVALID_PARAM", listError(err))
+		return
+	}
 
-	s.respondJSON(w, http.StatusOK, map[string]interface{}{
-		"airports": airports,
-		"query":    query,
-		"total":    len(airports),
-		"limit":    limit,
-		"offset":   offset,
-	})
+	data := map[string]interface{}{
+		"query": opts.Query,
+	}
+	addPagination(w, r, data, opts.Page, info)
+
+	if geo {
+		features := airportFeatures(results, func(res airports.SearchResult) *airports.Airport { return &res.Airport }, fields)
+		s.respondGeoJSON(w, http.StatusOK, features, data)
+		return
+	}
+
+	data["airports"] = fields.apply(results)
+	s.respondJSON(w, http.StatusOK, data)
 }
 
+// Default search radii, in the request's distance unit on every surface
+const (
+	nearbyDefaultRadius      = 50.0  // Nearby searches
+	geoIPNearbyDefaultRadius = 100.0 // Searches around an IP's location
+)
+
 // handleNearbyAirports finds airports near coordinates
 func (s *Server) handleNearbyAirports(w http.ResponseWriter, r *http.Request) {
 	latStr := r.URL.Query().Get("lat")
@@ -109,64 +167,328 @@ func (s *Server) handleNearbyAirports(w http.ResponseWriter, r *http.Request) {
 	unitsParam := r.URL.Query().Get("units")
 
 	lat, err := strconv.ParseFloat(latStr, 64)
-	if err != nil {
-		s.respondError(w, http.StatusBadRequest, "INVALID_PARAM", "Invalid latitude")
+	if err != nil || math.IsNaN(lat) || lat < -90 || lat > 90 {
+		s.respondFieldError(w, http.StatusBadRequest, "INVALID_PARAM", "Invalid latitude (must be between -90 and 90)", "lat")
 		return
 	}
 
 	lon, err := strconv.ParseFloat(lonStr, 64)
-	if err != nil {
-		s.respondError(w, http.StatusBadRequest, "INVALID_PARAM", "Invalid longitude")
+	if err != nil || math.IsNaN(lon) || lon < -180 || lon > 180 {
+		s.respondFieldError(w, http.StatusBadRequest, "INVALID_PARAM", "Invalid longitude (must be between -180 and 180)", "lon")
 		return
 	}
 
-	radius := 50.0
-	if radiusStr != "" {
-		radius, _ = strconv.ParseFloat(radiusStr, 64)
-	}
-	if radius > 500 {
-		radius = 500
+	// Parse unit system (default: server.default_units)
+	units := requestUnits(unitsParam)
+
+	radius, displayRadius, err := parseRadius(radiusStr, units, airports.DistanceToKm(nearbyDefaultRadius, units), nearbyMaxRadius())
+	if err != nil || math.IsNaN(radius) || radius < 0 {
+		s.respondFieldError(w, http.StatusBadRequest, "INVALID_PARAM", "Invalid radius (must be a non-negative number)", "radius")
+		return
 	}
 
 	limit := 20
 	if limitStr != "" {
 		limit, _ = strconv.Atoi(limitStr)
 	}
+	limit = min(limit, searchMaxResults())
 
-	// Parse unit system (default: imperial)
-	units := airports.ParseUnits(unitsParam)
+	fields, ok := s.parseFieldsParam(w, r, airports.AirportWithDistance{})
+	if !ok {
+		return
+	}
+	geo, ok := s.parseFormatParam(w, r)
+	if !ok {
+		return
+	}
 
 	// Get airports with distance information
 	airportsWithDist := s.airports.GetNearbyWithDistance(lat, lon, radius, limit, units)
 
-	// Convert radius for display
-	displayRadius, radiusUnit := airports.ConvertDistance(radius, units)
+	_, radiusUnit := airports.ConvertDistance(radius, units)
 
-	s.respondJSON(w, http.StatusOK, map[string]interface{}{
-		"airports": airportsWithDist,
-		"center":   map[string]float64{"lat": lat, "lon": lon},
-		"radius":   displayRadius,
+	data := map[string]interface{}{
+		"center":      map[string]float64{"lat": lat, "lon": lon},
+		"radius":      displayRadius,
 		"radius_unit": radiusUnit,
-		"units":    units,
-		"count":    len(airportsWithDist),
-	})
+		"units":       units,
+		"count":       len(airportsWithDist),
+	}
+
+	if geo {
+		features := airportFeatures(airportsWithDist, withDistanceAirport, fields)
+		s.respondGeoJSON(w, http.StatusOK, features, data)
+		return
+	}
+
+	data["airports"] = fields.apply(airportsWithDist)
+	s.respondJSON(w, http.StatusOK, data)
+}
+
+// handleNearestAirports returns the k closest airports at any distance
+func (s *Server) handleNearestAirports(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	lat, err := strconv.ParseFloat(query.Get("lat"), 64)
+	if err != nil || math.IsNaN(lat) || lat < -90 || lat > 90 {
+		s.respondFieldError(w, http.StatusBadRequest, "INVALID_PARAM", "Invalid latitude (must be between -90 and 90)", "lat")
+		return
+	}
+
+	lon, err := strconv.ParseFloat(query.Get("lon"), 64)
+	if err != nil || math.IsNaN(lon) || lon < -180 || lon > 180 {
+		s.respondFieldError(w, http.StatusBadRequest, "INVALID_PARAM", "Invalid longitude (must be between -180 and 180)", "lon")
+		return
+	}
+
+	k := 10
+	if kStr := query.Get("k"); kStr != "" {
+		k, err = strconv.Atoi(kStr)
+		if err != nil || k <= 0 {
+			s.respondFieldError(w, http.StatusBadRequest, "INVALID_PARAM", "Invalid k (must be a positive integer)", "k")
+			return
+		}
+	}
+	if k > 100 {
+		k = 100
+	}
+
+	filters, err := parseFilters(query)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "INVALID_PARAM", err.Error())
+		return
+	}
+
+	fields, ok := s.parseFieldsParam(w, r, airports.AirportWithDistance{})
+	if !ok {
+		return
+	}
+	geo, ok := s.parseFormatParam(w, r)
+	if !ok {
+		return
+	}
+
+	units := requestUnits(query.Get("units"))
+	nearest := s.airports.Nearest(lat, lon, k, filters, units)
+
+	data := map[string]interface{}{
+		"center": map[string]float64{"lat": lat, "lon": lon},
+		"k":      k,
+		"units":  units,
+		"count":  len(nearest),
+	}
+
+	if geo {
+		features := airportFeatures(nearest, withDistanceAirport, fields)
+		s.respondGeoJSON(w, http.StatusOK, features, data)
+		return
+	}
+
+	data["airports"] = fields.apply(nearest)
+	s.respondJSON(w, http.StatusOK, data)
 }
 
 // handleBBoxAirports finds airports in bounding box
 func (s *Server) handleBBoxAirports(w http.ResponseWriter, r *http.Request) {
-	minLat, _ := strconv.ParseFloat(r.URL.Query().Get("minLat"), 64)
-	maxLat, _ := strconv.ParseFloat(r.URL.Query().Get("maxLat"), 64)
-	minLon, _ := strconv.ParseFloat(r.URL.Query().Get("minLon"), 64)
-	maxLon, _ := strconv.ParseFloat(r.URL.Query().Get("maxLon"), 64)
+	minLat, maxLat, minLon, maxLon, field, err := parseBBox(r.URL.Query())
+	if err != nil {
+		s.respondFieldError(w, http.StatusBadRequest, "INVALID_PARAM", err.Error(), field)
+		return
+	}
+	bbox := map[string]interface{}{
+		"minLat":               minLat,
+		"maxLat":               maxLat,
+		"minLon":               minLon,
+		"maxLon":               maxLon,
+		"crosses_antimeridian": minLon > maxLon,
+	}
+
+	fields, ok := s.parseFieldsParam(w, r, airports.Airport{})
+	if !ok {
+		return
+	}
+	geo, ok := s.parseFormatParam(w, r)
+	if !ok {
+		return
+	}
+
+	cellDegrees, field, err := parseClusterCell(r.URL.Query(), minLon, maxLon)
+	if err != nil {
+		s.respondFieldError(w, http.StatusBadRequest, "INVALID_PARAM", err.Error(), field)
+		return
+	}
+	if cellDegrees > 0 {
+		clusters := s.airports.ClusterInBoundingBox(minLat, maxLat, minLon, maxLon, cellDegrees)
+		s.respondClusters(w, clusters, cellDegrees, bbox, fields, geo)
+		return
+	}
 
-	airports := s.airports.GetInBoundingBox(minLat, maxLat, minLon, maxLon)
+	results := s.airports.GetInBoundingBox(minLat, maxLat, minLon, maxLon)
+
+	if geo {
+		features := airportFeatures(results, func(apt *airports.Airport) *airports.Airport { return apt }, fields)
+		s.respondGeoJSON(w, http.StatusOK, features, map[string]interface{}{
+			"bbox":  bbox,
+			"count": len(results),
+		})
+		return
+	}
 
 	s.respondJSON(w, http.StatusOK, map[string]interface{}{
-		"airports": airports,
-		"count":    len(airports),
+		"airports": fields.apply(results),
+		"bbox":     bbox,
+		"count":    len(results),
 	})
 }
 
+// parseBBox reads a bounding box from either the bbox=minLon,minLat,maxLon,maxLat
+// shorthand or the minLat, maxLat, minLon and maxLon parameters. minLon may
+// exceed maxLon for a box that crosses the antimeridian. On error it also
+// returns the offending parameter.
+func parseBBox(query url.Values) (minLat, maxLat, minLon, maxLon float64, field string, err error) {
+	if bbox := query.Get("bbox"); bbox != "" {
+		parts := strings.Split(bbox, ",")
+		if len(parts) != 4 {
+			return 0, 0, 0, 0, "bbox", fmt.Errorf("bbox must be minLon,minLat,maxLon,maxLat")
+		}
+		var values [4]float64
+		for i, part := range parts {
+			values[i], err = strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil || math.IsNaN(values[i]) {
+				return 0, 0, 0, 0, "bbox", fmt.Errorf("bbox must be minLon,minLat,maxLon,maxLat (invalid number %q)", part)
+			}
+		}
+		minLon, minLat, maxLon, maxLat = values[0], values[1], values[2], values[3]
+		field = "bbox"
+	} else {
+		params := []struct {
+			name   string
+			target *float64
+		}{
+			{"minLat", &minLat},
+			{"maxLat", &maxLat},
+			{"minLon", &minLon},
+			{"maxLon", &maxLon},
+		}
+		for _, p := range params {
+			str := query.Get(p.name)
+			if str == "" {
+				return 0, 0, 0, 0, p.name, fmt.Errorf("missing required parameter %s (or use bbox=minLon,minLat,maxLon,maxLat)", p.name)
+			}
+			*p.target, err = strconv.ParseFloat(str, 64)
+			if err != nil || math.IsNaN(*p.target) {
+				return 0, 0, 0, 0, p.name, fmt.Errorf("invalid %s (must be a number)", p.name)
+			}
+		}
+	}
+
+	// With the shorthand, every range error is reported against bbox
+	fieldOr := func(name string) string {
+		if field != "" {
+			return field
+		}
+		return name
+	}
+
+	switch {
+	case minLat < -90 || minLat > 90:
+		return 0, 0, 0, 0, fieldOr("minLat"), fmt.Errorf("minLat must be between -90 and 90")
+	case maxLat < -90 || maxLat > 90:
+		return 0, 0, 0, 0, fieldOr("maxLat"), fmt.Errorf("maxLat must be between -90 and 90")
+	case minLon < -180 || minLon > 180:
+		return 0, 0, 0, 0, fieldOr("minLon"), fmt.Errorf("minLon must be between -180 and 180")
+	case maxLon < -180 || maxLon > 180:
+		return 0, 0, 0, 0, fieldOr("maxLon"), fmt.Errorf("maxLon must be between -180 and 180")
+	case minLat > maxLat:
+		return 0, 0, 0, 0, fieldOr("minLat"), fmt.Errorf("minLat must not exceed maxLat")
+	}
+
+	return minLat, maxLat, minLon, maxLon, "", nil
+}
+
+// Cluster sizing for bounding box clustering
+const (
+	clusterCellPixels   = 64 // Cluster cell width on a 256px map tile at the requested zoom
+	clusterDefaultCells = 16 // Cells across the box for cluster=true without a zoom
+)
+
+// parseClusterCell reads the zoom and cluster parameters and returns the
+// cluster cell size in degrees, or 0 when clustering is off. A box with
+// equal longitudes has no width to divide, so cluster=true leaves it
+// unclustered. On error it also returns the offending parameter.
+func parseClusterCell(query url.Values, minLon, maxLon float64) (float64, string, error) {
+	if zoomStr := query.Get("zoom"); zoomStr != "" {
+		zoom, err := strconv.Atoi(zoomStr)
+		if err != nil || zoom < 0 || zoom > tileMaxZoom {
+			return 0, "zoom", fmt.Errorf("invalid zoom (must be 0-%d)", tileMaxZoom)
+		}
+		return 360 / math.Exp2(float64(zoom)) * clusterCellPixels / 256, "", nil
+	}
+
+	if clusterStr := query.Get("cluster"); clusterStr != "" {
+		cluster, err := strconv.ParseBool(clusterStr)
+		if err != nil {
+			return 0, "cluster", fmt.Errorf("invalid cluster (must be true or false)")
+		}
+		if cluster {
+			// Only a box whose minLon exceeds maxLon crosses the antimeridian
+			width := maxLon - minLon
+			if width < 0 {
+				width += 360
+			}
+			return width / clusterDefaultCells, "", nil
+		}
+	}
+
+	return 0, "", nil
+}
+
+// respondClusters writes bounding box clusters as JSON or GeoJSON. The
+// representative airport of each cluster honours fields.
+func (s *Server) respondClusters(w http.ResponseWriter, clusters []airports.Cluster, cellDegrees float64, bbox map[string]interface{}, fields fieldSet, geo bool) {
+	total := 0
+	for _, c := range clusters {
+		total += c.Count
+	}
+
+	data := map[string]interface{}{
+		"bbox":         bbox,
+		"count":        len(clusters),
+		"total":        total,
+		"cell_degrees": cellDegrees,
+	}
+
+	if geo {
+		features := make([]geoJSONFeature, len(clusters))
+		for i, c := range clusters {
+			features[i] = geoJSONFeature{
+				Type: "Feature",
+				Geometry: geoJSONGeometry{
+					Type:        "Point",
+					Coordinates: []float64{c.Centroid.Lon, c.Centroid.Lat},
+				},
+				Properties: map[string]interface{}{
+					"count":   c.Count,
+					"airport": fields.apply(c.Airport),
+				},
+			}
+		}
+		s.respondGeoJSON(w, http.StatusOK, features, data)
+		return
+	}
+
+	out := make([]map[string]interface{}, len(clusters))
+	for i, c := range clusters {
+		out[i] = map[string]interface{}{
+			"centroid": c.Centroid,
+			"count":    c.Count,
+			"airport":  fields.apply(c.Airport),
+		}
+	}
+	data["clusters"] = out
+	s.respondJSON(w, http.StatusOK, data)
+}
+
 // handleAutocomplete provides autocomplete suggestions
 func (s *Server) handleAutocomplete(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query().Get("q")
@@ -181,10 +503,15 @@ func (s *Server) handleAutocomplete(w http.ResponseWriter, r *http.Request) {
 		limit = 10
 	}
 
-	airports := s.airports.Search(query, limit, 0)
+	fields, ok := s.parseFieldsParam(w, r, airports.SearchResult{})
+	if !ok {
+		return
+	}
+
+	suggestions, _ := s.airports.Search(query, limit, 0)
 
 	s.respondJSON(w, http.StatusOK, map[string]interface{}{
-		"suggestions": airports,
+		"suggestions": fields.apply(suggestions),
 		"query":       query,
 	})
 }
@@ -278,35 +605,185 @@ func (s *Server) handleGeoIPNearbyAirports(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	// Get radius, limit, and units
-	radius := 100.0
-	if r.URL.Query().Get("radius") != "" {
-		radius, _ = strconv.ParseFloat(r.URL.Query().Get("radius"), 64)
+	// Get units, radius (in the unit system's distance unit) and limit
+	unitsParam := r.URL.Query().Get("units")
+	units := requestUnits(unitsParam)
+
+	radius, displayRadius, err := parseRadius(r.URL.Query().Get("radius"), units, airports.DistanceToKm(geoIPNearbyDefaultRadius, units), nearbyMaxRadius())
+	if err != nil || math.IsNaN(radius) || radius < 0 {
+		s.respondFieldError(w, http.StatusBadRequest, "INVALID_PARAM", "Invalid radius (must be a non-negative number)", "radius")
+		return
 	}
 
 	limit := 10
 	if r.URL.Query().Get("limit") != "" {
 		limit, _ = strconv.Atoi(r.URL.Query().Get("limit"))
 	}
+	limit = min(limit, searchMaxResults())
 
-	unitsParam := r.URL.Query().Get("units")
-	units := airports.ParseUnits(unitsParam)
+	fields, ok := s.parseFieldsParam(w, r, airports.AirportWithDistance{})
+	if !ok {
+		return
+	}
 
 	// Find nearby airports with distance
 	airportsNearby := s.airports.GetNearbyWithDistance(location.Latitude, location.Longitude, radius, limit, units)
 
-	// Convert radius for display
-	displayRadius, radiusUnit := airports.ConvertDistance(radius, units)
+	_, radiusUnit := airports.ConvertDistance(radius, units)
 
 	s.respondJSON(w, http.StatusOK, map[string]interface{}{
 		"location":        location,
-		"nearby_airports": airportsNearby,
+		"nearby_airports": fields.apply(airportsNearby),
 		"radius":          displayRadius,
 		"radius_unit":     radiusUnit,
 		"units":           units,
 	})
 }
 
+// parseFilters reads airport filters from query parameters
+func parseFilters(query url.Values) (airports.Filters, error) {
+	filters := airports.Filters{
+		Country:    query.Get("country"),
+		State:      query.Get("state"),
+		City:       query.Get("city"),
+		Tz:         query.Get("tz"),
+		NamePrefix: query.Get("name_prefix"),
+	}
+
+	if hasIATA := query.Get("has_iata"); hasIATA != "" {
+		val, err := strconv.ParseBool(hasIATA)
+		if err != nil {
+			return filters, fmt.Errorf("invalid has_iata (must be true or false)")
+		}
+		filters.HasIATA = &val
+	}
+
+	var err error
+	if filters.MinElevation, err = parseOptionalInt(query, "min_elevation"); err != nil {
+		return filters, err
+	}
+	if filters.MaxElevation, err = parseOptionalInt(query, "max_elevation"); err != nil {
+		return filters, err
+	}
+
+	if filters.MinElevation != nil && filters.MaxElevation != nil && *filters.MinElevation > *filters.MaxElevation {
+		return filters, fmt.Errorf("min_elevation must not exceed max_elevation")
+	}
+
+	if filters.Types, err = parseTypes(query, "type"); err != nil {
+		return filters, err
+	}
+	if filters.ExcludeTypes, err = parseTypes(query, "exclude_type"); err != nil {
+		return filters, err
+	}
+
+	if scheduled := query.Get("scheduled_service"); scheduled != "" {
+		val, err := strconv.ParseBool(scheduled)
+		if err != nil {
+			return filters, fmt.Errorf("invalid scheduled_service (must be true or false)")
+		}
+		filters.ScheduledService = &val
+	}
+
+	if filters.MinRunwayLength, err = parseOptionalInt(query, "min_runway_length"); err != nil {
+		return filters, err
+	}
+	filters.RunwaySurface = query.Get("runway_surface")
+
+	return filters, nil
+}
+
+// parseTypes reads a comma-separated list of airport types, returning nil
+// when absent
+func parseTypes(query url.Values, param string) ([]string, error) {
+	str := query.Get(param)
+	if str == "" {
+		return nil, nil
+	}
+	var types []string
+	for _, name := range strings.Split(str, ",") {
+		t, ok := airports.ParseAirportType(name)
+		if !ok {
+			return nil, fmt.Errorf("invalid %s %q (must be one of %s)", param, strings.TrimSpace(name), strings.Join(airports.AirportTypes, ", "))
+		}
+		types = append(types, t)
+	}
+	return types, nil
+}
+
+// parseOptionalInt reads an integer query parameter, returning nil when absent
+func parseOptionalInt(query url.Values, param string) (*int, error) {
+	str := query.Get(param)
+	if str == "" {
+		return nil, nil
+	}
+	val, err := strconv.Atoi(str)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s (must be an integer)", param)
+	}
+	return &val, nil
+}
+
+// parseRadius reads a radius given in the unit system's distance unit. It
+// returns the radius in km, capped at maxKm, and the radius to display in
+// that unit. An empty value selects defaultKm.
+func parseRadius(value, units string, defaultKm, maxKm float64) (radiusKm, display float64, err error) {
+	if value == "" {
+		radiusKm = math.Min(defaultKm, maxKm)
+		display, _ = airports.ConvertDistance(radiusKm, units)
+		return radiusKm, display, nil
+	}
+
+	display, err = strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	radiusKm = airports.DistanceToKm(display, units)
+	if radiusKm > maxKm {
+		radiusKm = maxKm
+		display, _ = airports.ConvertDistance(maxKm, units)
+	}
+	return radiusKm, display, nil
+}
+
+// parseListOptions reads the search query, filters, sort order and page
+// from query parameters
+func parseListOptions(query url.Values) (airports.ListOptions, error) {
+	filters, err := parseFilters(query)
+	if err != nil {
+		return airports.ListOptions{}, err
+	}
+
+	opts := airports.ListOptions{
+		Query:   query.Get("q"),
+		Filters: filters,
+		Sort:    strings.ToLower(query.Get("sort")),
+		Page:    parsePage(query, 50, searchMaxResults()),
+	}
+
+	if !airports.ValidSort(opts.Sort) {
+		return opts, fmt.Errorf("invalid sort (must be icao, iata, name, elevation, country or relevance)")
+	}
+
+	switch strings.ToLower(query.Get("order")) {
+	case "", "asc":
+	case "desc":
+		opts.Desc = true
+	default:
+		return opts, fmt.Errorf("invalid order (must be asc or desc)")
+	}
+
+	return opts, nil
+}
+
+// listError describes a List or SearchPage error for clients
+func listError(err error) string {
+	if errors.Is(err, airports.ErrInvalidCursor) {
+		return "Invalid cursor"
+	}
+	return err.Error()
+}
+
 // handleDebugRoutes shows all registered routes
 func (s *Server) handleDebugRoutes(w http.ResponseWriter, r *http.Request) {
 	routes := []string{}
@@ -0,0 +1,210 @@
+package server
+
+import "math"
+
+// Mapbox Vector Tile (v2.1) encoding. Only what the airport layer needs is
+// implemented: one layer of Point features with string and integer values.
+
+// mvtExtent is the tile coordinate range (0..4096 across a tile)
+const mvtExtent = 4096
+
+// Protocol buffer field numbers from vector_tile.proto
+const (
+	mvtTileLayers = 3
+
+	mvtLayerName     = 1
+	mvtLayerFeatures = 2
+	mvtLayerKeys     = 3
+	mvtLayerValues   = 4
+	mvtLayerExtent   = 5
+	mvtLayerVersion  = 15
+
+	mvtFeatureID       = 1
+	mvtFeatureTags     = 2
+	mvtFeatureType     = 3
+	mvtFeatureGeometry = 4
+
+	mvtValueString = 1
+	mvtValueSint   = 6
+
+	mvtGeomPoint = 1
+	mvtMoveTo    = 1
+)
+
+// Protocol buffer wire types
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+// mvtLayer accumulates Point features and their shared key/value tables
+type mvtLayer struct {
+	name     string
+	features [][]byte
+	keys     []string
+	keyIndex map[string]uint32
+	values   [][]byte
+	valIndex map[string]uint32
+}
+
+// newMVTLayer creates an empty layer
+func newMVTLayer(name string) *mvtLayer {
+	return &mvtLayer{
+		name:     name,
+		keyIndex: make(map[string]uint32),
+		valIndex: make(map[string]uint32),
+	}
+}
+
+// mvtProperty is a feature property; exactly one of str or num is used
+type mvtProperty struct {
+	key   string
+	str   string
+	num   int64
+	isNum bool
+}
+
+// addPoint adds a Point feature at tile coordinates (x, y)
+func (l *mvtLayer) addPoint(id uint64, x, y int, props []mvtProperty) {
+	tags := make([]uint64, 0, len(props)*2)
+	for _, p := range props {
+		if !p.isNum && p.str == "" {
+			continue
+		}
+		tags = append(tags, uint64(l.key(p.key)), uint64(l.value(p)))
+	}
+
+	var f pbBuffer
+	if id != 0 {
+		f.varintField(mvtFeatureID, id)
+	}
+	f.packedField(mvtFeatureTags, tags)
+	f.varintField(mvtFeatureType, mvtGeomPoint)
+	f.packedField(mvtFeatureGeometry, []uint64{
+		mvtMoveTo | 1<<3, // MoveTo, count 1
+		zigzag(int64(x)),
+		zigzag(int64(y)),
+	})
+	l.features = append(l.features, f)
+}
+
+// key returns the index of a property key, adding it if new
+func (l *mvtLayer) key(k string) uint32 {
+	if idx, ok := l.keyIndex[k]; ok {
+		return idx
+	}
+	idx := uint32(len(l.keys))
+	l.keys = append(l.keys, k)
+	l.keyIndex[k] = idx
+	return idx
+}
+
+// value returns the index of a property value, adding it if new
+func (l *mvtLayer) value(p mvtProperty) uint32 {
+	var v pbBuffer
+	if p.isNum {
+		v.varintField(mvtValueSint, zigzag(p.num))
+	} else {
+		v.bytesField(mvtValueString, []byte(p.str))
+	}
+
+	lookup := string(v)
+	if idx, ok := l.valIndex[lookup]; ok {
+		return idx
+	}
+	idx := uint32(len(l.values))
+	l.values = append(l.values, v)
+	l.valIndex[lookup] = idx
+	return idx
+}
+
+// encodeTile encodes layers as a vector tile. Empty layers are omitted.
+func encodeTile(layers ...*mvtLayer) []byte {
+	var tile pbBuffer
+	for _, l := range layers {
+		if len(l.features) == 0 {
+			continue
+		}
+
+		var layer pbBuffer
+		layer.varintField(mvtLayerVersion, 2)
+		layer.bytesField(mvtLayerName, []byte(l.name))
+		for _, f := range l.features {
+			layer.bytesField(mvtLayerFeatures, f)
+		}
+		for _, k := range l.keys {
+			layer.bytesField(mvtLayerKeys, []byte(k))
+		}
+		for _, v := range l.values {
+			layer.bytesField(mvtLayerValues, v)
+		}
+		layer.varintField(mvtLayerExtent, mvtExtent)
+
+		tile.bytesField(mvtTileLayers, layer)
+	}
+	return tile
+}
+
+// pbBuffer is a minimal protocol buffer writer
+type pbBuffer []byte
+
+func (b *pbBuffer) varint(v uint64) {
+	for v >= 0x80 {
+		*b = append(*b, byte(v)|0x80)
+		v >>= 7
+	}
+	*b = append(*b, byte(v))
+}
+
+func (b *pbBuffer) tag(field, wireType int) {
+	b.varint(uint64(field<<3 | wireType))
+}
+
+func (b *pbBuffer) varintField(field int, v uint64) {
+	b.tag(field, wireVarint)
+	b.varint(v)
+}
+
+func (b *pbBuffer) bytesField(field int, data []byte) {
+	b.tag(field, wireBytes)
+	b.varint(uint64(len(data)))
+	*b = append(*b, data...)
+}
+
+func (b *pbBuffer) packedField(field int, values []uint64) {
+	var packed pbBuffer
+	for _, v := range values {
+		packed.varint(v)
+	}
+	b.bytesField(field, packed)
+}
+
+// zigzag encodes a signed integer for sint fields and geometry parameters
+func zigzag(n int64) uint64 {
+	return uint64((n << 1) ^ (n >> 63))
+}
+
+// tileBounds returns the lon/lat bounds of a Web Mercator (XYZ) tile
+func tileBounds(z, x, y int) (minLat, maxLat, minLon, maxLon float64) {
+	n := math.Exp2(float64(z))
+	minLon = float64(x)/n*360 - 180
+	maxLon = float64(x+1)/n*360 - 180
+	maxLat = tileLat(float64(y), n)
+	minLat = tileLat(float64(y+1), n)
+	return minLat, maxLat, minLon, maxLon
+}
+
+// tileLat returns the latitude of a tile row edge
+func tileLat(y, n float64) float64 {
+	return math.Atan(math.Sinh(math.Pi*(1-2*y/n))) * 180 / math.Pi
+}
+
+// tilePixel projects a point into the tile's coordinate space (0..mvtExtent)
+func tilePixel(z, x, y int, lat, lon float64) (int, int) {
+	n := math.Exp2(float64(z))
+	latRad := lat * math.Pi / 180
+	worldX := (lon + 180) / 360 * n
+	worldY := (1 - math.Log(math.Tan(latRad)+1/math.Cos(latRad))/math.Pi) / 2 * n
+	return int(math.Round((worldX - float64(x)) * mvtExtent)),
+		int(math.Round((worldY - float64(y)) * mvtExtent))
+}
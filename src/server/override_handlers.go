@@ -0,0 +1,204 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/apimgr/airports/src/airports"
+	"github.com/apimgr/airports/src/database"
+	"github.com/go-chi/chi/v5"
+)
+
+// Override audit operations
+const (
+	overrideCreate  = "create"
+	overrideReplace = "replace"
+	overridePatch   = "patch"
+	overrideDelete  = "delete"
+	overrideRevert  = "revert"
+)
+
+const (
+	maxOverrideBodyBytes = 1 << 20 // Airport override request bodies
+	overrideHistoryLimit = 100     // Audit entries returned per airport
+)
+
+// LoadAirportOverrides applies the airport overrides stored in the
+// database, returning how many there are
+func LoadAirportOverrides(svc *airports.Service) (int, error) {
+	records, err := database.GetAirportOverrides()
+	if err != nil {
+		return 0, err
+	}
+
+	overrides := make([]airports.Override, len(records))
+	for i, rec := range records {
+		overrides[i] = airports.Override{
+			ICAO:      rec.ICAO,
+			Action:    rec.Action,
+			Fields:    rec.Fields,
+			UpdatedAt: rec.UpdatedAt,
+		}
+	}
+	svc.LoadOverrides(overrides)
+	return len(overrides), nil
+}
+
+// handleAdminAirportOverridesAPI lists the airport overrides in effect
+func (s *Server) handleAdminAirportOverridesAPI(w http.ResponseWriter, r *http.Request) {
+	overrides := s.airports.Overrides()
+	s.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"overrides": overrides,
+		"count":     len(overrides),
+	})
+}
+
+// handleAdminAirportCreateAPI adds an airport the dataset lacks
+func (s *Server) handleAdminAirportCreateAPI(w http.ResponseWriter, r *http.Request) {
+	var fields json.RawMessage
+	if !s.decodeJSONBody(w, r, maxOverrideBodyBytes, &fields) {
+		return
+	}
+	s.changeAirportOverride(w, r, overrideCreate, http.StatusCreated, func(icao string, save func(airports.OverrideChange) error) (airports.OverrideChange, error) {
+		return s.airports.CreateAirport(icao, fields, save)
+	})
+}
+
+// handleAdminAirportReplaceAPI replaces every field of an airport, adding
+// it if needed
+func (s *Server) handleAdminAirportReplaceAPI(w http.ResponseWriter, r *http.Request) {
+	var fields json.RawMessage
+	if !s.decodeJSONBody(w, r, maxOverrideBodyBytes, &fields) {
+		return
+	}
+	s.changeAirportOverride(w, r, overrideReplace, http.StatusOK, func(icao string, save func(airports.OverrideChange) error) (airports.OverrideChange, error) {
+		return s.airports.ReplaceAirport(icao, fields, save)
+	})
+}
+
+// handleAdminAirportPatchAPI changes some fields of an airport
+func (s *Server) handleAdminAirportPatchAPI(w http.ResponseWriter, r *http.Request) {
+	var fields json.RawMessage
+	if !s.decodeJSONBody(w, r, maxOverrideBodyBytes, &fields) {
+		return
+	}
+	s.changeAirportOverride(w, r, overridePatch, http.StatusOK, func(icao string, save func(airports.OverrideChange) error) (airports.OverrideChange, error) {
+		return s.airports.PatchAirport(icao, fields, save)
+	})
+}
+
+// handleAdminAirportDeleteAPI hides an airport
+func (s *Server) handleAdminAirportDeleteAPI(w http.ResponseWriter, r *http.Request) {
+	s.changeAirportOverride(w, r, overrideDelete, http.StatusOK, s.airports.DeleteAirport)
+}
+
+// handleAdminAirportRevertAPI removes an airport's override, serving the
+// dataset's version again
+func (s *Server) handleAdminAirportRevertAPI(w http.ResponseWriter, r *http.Request) {
+	s.changeAirportOverride(w, r, overrideRevert, http.StatusOK, s.airports.RevertAirport)
+}
+
+// handleAdminAirportHistoryAPI returns the audit history of an airport's
+// overrides, newest first
+func (s *Server) handleAdminAirportHistoryAPI(w http.ResponseWriter, r *http.Request) {
+	if database.DB == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "DB_UNAVAILABLE", "Airport override history needs a database")
+		return
+	}
+
+	icao := strings.ToUpper(chi.URLParam(r, "icao"))
+	history, err := database.GetAirportOverrideHistory(icao, overrideHistoryLimit)
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, "FETCH_FAILED", err.Error())
+		return
+	}
+
+	s.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"icao":    icao,
+		"history": history,
+		"count":   len(history),
+	})
+}
+
+// changeAirportOverride runs an override change for the airport in the
+// URL. The change is stored with its audit entry before it is served, so
+// a database failure leaves the served data untouched.
+func (s *Server) changeAirportOverride(w http.ResponseWriter, r *http.Request, operation string, status int,
+	change func(icao string, save func(airports.OverrideChange) error) (airports.OverrideChange, error)) {
+	if database.DB == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "DB_UNAVAILABLE", "Airport overrides need a database")
+		return
+	}
+
+	icao := chi.URLParam(r, "icao")
+	remoteAddr := remoteHost(r.RemoteAddr)
+
+	var saveErr error
+	result, err := change(icao, func(c airports.OverrideChange) error {
+		saveErr = database.SaveAirportOverride(overrideRecord(c.Override), &database.AirportOverrideChange{
+			ICAO:       c.ICAO,
+			Operation:  operation,
+			Before:     airportJSON(c.Before),
+			After:      airportJSON(c.After),
+			Actor:      adminIdentity(r),
+			RemoteAddr: remoteAddr,
+			CreatedAt:  time.Now().UTC(),
+		})
+		return saveErr
+	})
+
+	var fieldErr *airports.FieldError
+	switch {
+	case err == nil:
+	case saveErr != nil:
+		s.respondError(w, http.StatusInternalServerError, "UPDATE_FAILED", saveErr.Error())
+		return
+	case errors.As(err, &fieldErr):
+		s.respondFieldError(w, http.StatusBadRequest, "INVALID_AIRPORT", fieldErr.Message, fieldErr.Field)
+		return
+	case errors.Is(err, airports.ErrAirportNotFound):
+		s.respondError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("Airport not found: %s", icao))
+		return
+	case errors.Is(err, airports.ErrAirportExists):
+		s.respondError(w, http.StatusConflict, "AIRPORT_EXISTS", fmt.Sprintf("Airport already exists: %s", icao))
+		return
+	case errors.Is(err, airports.ErrNoOverride):
+		s.respondError(w, http.StatusNotFound, "OVERRIDE_NOT_FOUND", fmt.Sprintf("Airport has no override: %s", icao))
+		return
+	default:
+		s.respondError(w, http.StatusInternalServerError, "UPDATE_FAILED", err.Error())
+		return
+	}
+
+	s.respondJSON(w, status, map[string]interface{}{
+		"icao":     result.ICAO,
+		"airport":  result.After,
+		"override": result.Override,
+	})
+}
+
+// overrideRecord converts an override for storage; nil removes it
+func overrideRecord(o *airports.Override) *database.AirportOverride {
+	if o == nil {
+		return nil
+	}
+	return &database.AirportOverride{
+		ICAO:      o.ICAO,
+		Action:    o.Action,
+		Fields:    o.Fields,
+		UpdatedAt: o.UpdatedAt,
+	}
+}
+
+// airportJSON encodes an airport for the audit history; nil stays empty
+func airportJSON(apt *airports.Airport) json.RawMessage {
+	if apt == nil {
+		return nil
+	}
+	raw, _ := json.Marshal(apt)
+	return raw
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/apimgr/airports/src/airports"
+)
+
+// parsePage reads the limit, offset and cursor query parameters. Limits
+// outside 1..maxLimit fall back to defaultLimit, itself capped at maxLimit.
+func parsePage(query url.Values, defaultLimit, maxLimit int) airports.Page {
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	offset, _ := strconv.Atoi(query.Get("offset"))
+
+	if limit <= 0 || limit > maxLimit {
+		limit = min(defaultLimit, maxLimit)
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	return airports.Page{
+		Limit:  limit,
+		Offset: offset,
+		Cursor: query.Get("cursor"),
+	}
+}
+
+// addPagination adds total, limit, offset, next/prev links and the next
+// cursor to response data, and sets the matching Link header. Requests that
+// pass a cursor (even an empty one) get cursor-based next links; cursor
+// pages are forward-only, so they have no prev link.
+func addPagination(w http.ResponseWriter, r *http.Request, data map[string]interface{}, page airports.Page, info airports.PageInfo) {
+	cursorMode := r.URL.Query().Has("cursor")
+
+	var next, prev interface{}
+	links := []string{}
+	addLink := func(rel string, params map[string]string) string {
+		link := pageURL(r, params)
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, link, rel))
+		return link
+	}
+
+	if info.NextCursor != "" {
+		if cursorMode {
+			next = addLink("next", map[string]string{"cursor": info.NextCursor, "offset": ""})
+		} else {
+			next = addLink("next", map[string]string{"offset": strconv.Itoa(info.Offset + page.Limit)})
+		}
+	}
+
+	if !cursorMode {
+		if info.Offset > 0 {
+			prev = addLink("prev", map[string]string{"offset": strconv.Itoa(max(info.Offset-page.Limit, 0))})
+		}
+		addLink("first", map[string]string{"offset": "0"})
+		if info.Total > 0 {
+			last := (info.Total - 1) / page.Limit * page.Limit
+			addLink("last", map[string]string{"offset": strconv.Itoa(last)})
+		}
+	}
+
+	data["total"] = info.Total
+	data["limit"] = page.Limit
+	data["offset"] = info.Offset
+	data["next"] = next
+	data["prev"] = prev
+	if info.NextCursor != "" {
+		data["next_cursor"] = info.NextCursor
+	} else {
+		data["next_cursor"] = nil
+	}
+
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+}
+
+// pageURL returns the request URL with query parameters replaced. Empty
+// values remove the parameter.
+func pageURL(r *http.Request, params map[string]string) string {
+	query := r.URL.Query()
+	for key, value := range params {
+		if value == "" {
+			query.Del(key)
+		} else {
+			query.Set(key, value)
+		}
+	}
+	if _, ok := params["cursor"]; !ok {
+		query.Del("cursor")
+	}
+
+	u := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+	return u.String()
+}
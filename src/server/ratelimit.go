@@ -0,0 +1,121 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rateLimiter holds a token bucket per client. Each bucket holds up to a
+// minute's worth of requests and refills continuously, so a client may
+// burst up to the limit and then continues at the per-minute rate.
+type rateLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// tokenBucket is one client's remaining allowance
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// rateLimitResult describes a client's allowance after a request
+type rateLimitResult struct {
+	allowed    bool
+	limit      int
+	remaining  int
+	retryAfter time.Duration // Until a request is allowed again; 0 when allowed
+	reset      time.Time     // When the bucket is full again
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{buckets: make(map[string]*tokenBucket)}
+}
+
+// take spends one token from a client's bucket. limit is read on every
+// request, so a changed setting applies to existing buckets at once.
+func (l *rateLimiter) take(client string, limit int, now time.Time) rateLimitResult {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	perSecond := float64(limit) / 60
+	l.sweep(perSecond, limit, now)
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &tokenBucket{tokens: float64(limit), updated: now}
+		l.buckets[client] = b
+	}
+	b.tokens = math.Min(float64(limit), b.tokens+now.Sub(b.updated).Seconds()*perSecond)
+	b.updated = now
+
+	result := rateLimitResult{limit: limit}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.allowed = true
+	} else {
+		result.retryAfter = time.Duration((1 - b.tokens) / perSecond * float64(time.Second))
+	}
+	result.remaining = int(b.tokens)
+	result.reset = now.Add(time.Duration((float64(limit) - b.tokens) / perSecond * float64(time.Second)))
+	return result
+}
+
+// sweep drops buckets that have refilled completely, at most once a minute,
+// so clients that have gone away do not accumulate
+func (l *rateLimiter) sweep(perSecond float64, limit int, now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	for client, b := range l.buckets {
+		if b.tokens+now.Sub(b.updated).Seconds()*perSecond >= float64(limit) {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// rateLimit enforces the api.rate_limit_* settings on each client, keyed by
+// API key when the request has a configured one and by peer IP otherwise.
+// Requests with valid admin credentials are not limited.
+func (s *Server) rateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit, enabled := rateLimitRequests()
+		if !enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if _, admin := authenticateAdmin(r); admin {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// realIP has already resolved the address forwarded by a trusted
+		// proxy; headers from anyone else must not pick the bucket
+		client := "ip:" + remoteHost(r.RemoteAddr)
+		if key := r.Header.Get("X-API-Key"); key != "" && rateLimitKey(key) {
+			client = "key:" + key
+		}
+
+		result := s.limiter.take(client, limit, time.Now())
+
+		h := w.Header()
+		h.Set("X-RateLimit-Limit", strconv.Itoa(result.limit))
+		h.Set("X-RateLimit-Remaining", strconv.Itoa(result.remaining))
+		h.Set("X-RateLimit-Reset", strconv.FormatInt(int64(math.Ceil(float64(result.reset.UnixNano())/1e9)), 10))
+
+		if !result.allowed {
+			retryAfter := int(math.Ceil(result.retryAfter.Seconds()))
+			h.Set("Retry-After", strconv.Itoa(retryAfter))
+			s.respondError(w, http.StatusTooManyRequests, "RATE_LIMIT_EXCEEDED",
+				fmt.Sprintf("Rate limit of %d requests per minute exceeded, retry in %d seconds", result.limit, retryAfter))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
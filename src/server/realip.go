@@ -0,0 +1,71 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// realIP sets the request's remote address to the client address forwarded
+// by a trusted proxy (server.trusted_proxies). Forwarding headers from any
+// other peer are ignored, so clients cannot choose the address they are
+// logged, audited and rate limited by.
+func realIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if proxies := trustedProxies(); len(proxies) > 0 {
+			if ip := forwardedClientIP(r, proxies); ip != "" {
+				r.RemoteAddr = ip
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// forwardedClientIP returns the client address a trusted peer forwarded, or
+// "" when the peer is not trusted or forwarded no valid address.
+// X-Forwarded-For is read from the right, skipping trusted proxies: entries
+// left of the first untrusted one may have been sent by the client.
+func forwardedClientIP(r *http.Request, proxies []*net.IPNet) string {
+	if !inNetworks(net.ParseIP(remoteHost(r.RemoteAddr)), proxies) {
+		return ""
+	}
+
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		hops := strings.Split(xff, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := net.ParseIP(strings.TrimSpace(hops[i]))
+			if ip == nil {
+				return ""
+			}
+			if i == 0 || !inNetworks(ip, proxies) {
+				return ip.String()
+			}
+		}
+	}
+
+	if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+		return ip.String()
+	}
+	return ""
+}
+
+// remoteHost strips the port from a remote address
+func remoteHost(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// inNetworks reports whether ip is in any of the networks
+func inNetworks(ip net.IP, networks []*net.IPNet) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/apimgr/airports/src/airports"
+)
+
+// Route request limits
+const (
+	routeMaxVia        = 20      // Intermediate stops
+	routeMaxWaypoints  = 1000    // Interpolated points per leg
+	matrixMaxAirports  = 500     // Airports in one distance matrix
+	matrixMaxBodyBytes = 1 << 20 // Distance matrix request body
+)
+
+// handleRoute computes the great-circle route from one airport to another,
+// optionally through intermediate airports
+func (s *Server) handleRoute(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	waypoints := 0
+	if waypointsStr := query.Get("waypoints"); waypointsStr != "" {
+		var err error
+		waypoints, err = strconv.Atoi(waypointsStr)
+		if err != nil || waypoints < 0 || waypoints > routeMaxWaypoints {
+			s.respondFieldError(w, http.StatusBadRequest, "INVALID_PARAM",
+				fmt.Sprintf("Invalid waypoints (must be 0-%d)", routeMaxWaypoints), "waypoints")
+			return
+		}
+	}
+
+	// via may be repeated or comma-separated
+	var via []string
+	for _, value := range query["via"] {
+		for _, code := range strings.Split(value, ",") {
+			if code = strings.TrimSpace(code); code != "" {
+				via = append(via, code)
+			}
+		}
+	}
+	if len(via) > routeMaxVia {
+		s.respondFieldError(w, http.StatusBadRequest, "INVALID_PARAM",
+			fmt.Sprintf("Too many via airports (max %d)", routeMaxVia), "via")
+		return
+	}
+
+	type stop struct {
+		param, code string
+	}
+	stops := []stop{{"from", strings.TrimSpace(query.Get("from"))}}
+	for _, code := range via {
+		stops = append(stops, stop{"via", code})
+	}
+	stops = append(stops, stop{"to", strings.TrimSpace(query.Get("to"))})
+
+	resolved := make([]*airports.Airport, len(stops))
+	for i, st := range stops {
+		if st.code == "" {
+			s.respondFieldError(w, http.StatusBadRequest, "INVALID_PARAM", "missing required parameter "+st.param, st.param)
+			return
+		}
+		apt, err := s.airports.GetByCode(st.code)
+		if err != nil {
+			s.respondFieldError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("Airport not found: %s", st.code), st.param)
+			return
+		}
+		resolved[i] = apt
+	}
+
+	route := airports.PlanRoute(resolved, waypoints)
+
+	legs := make([]map[string]interface{}, len(route.Legs))
+	var path [][]float64
+	for i, leg := range route.Legs {
+		legs[i] = map[string]interface{}{
+			"from":            leg.From.ICAO,
+			"to":              leg.To.ICAO,
+			"distance":        leg.Distance,
+			"initial_bearing": leg.InitialBearing,
+			"final_bearing":   leg.FinalBearing,
+			"midpoint":        leg.Midpoint,
+		}
+		if leg.Waypoints != nil {
+			line := lineStringCoordinates(leg.Waypoints)
+			legs[i]["waypoints"] = geoJSONGeometry{Type: "LineString", Coordinates: line}
+
+			// Legs share their end airports, so skip each later leg's first point
+			if len(path) > 0 {
+				line = line[1:]
+			}
+			path = append(path, line...)
+		}
+	}
+
+	data := map[string]interface{}{
+		"airports": resolved,
+		"legs":     legs,
+		"distance": route.Distance,
+	}
+	if path != nil {
+		data["waypoints"] = geoJSONGeometry{Type: "LineString", Coordinates: path}
+	}
+
+	s.respondJSON(w, http.StatusOK, data)
+}
+
+// lineStringCoordinates converts points to GeoJSON [lon, lat] positions
+func lineStringCoordinates(points []airports.Coordinates) [][]float64 {
+	line := make([][]float64, len(points))
+	for i, p := range points {
+		line[i] = []float64{p.Lon, p.Lat}
+	}
+	return line
+}
+
+// handleDistanceMatrix computes the distance and bearing between every pair
+// of airports in the request body. Codes that cannot be resolved are
+// reported with their index and leave null rows and columns, so the matrix
+// still lines up with the request.
+func (s *Server) handleDistanceMatrix(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Codes []string `json:"codes"`
+	}
+	if !s.decodeJSONBody(w, r, matrixMaxBodyBytes, &req) {
+		return
+	}
+
+	if len(req.Codes) == 0 {
+		s.respondFieldError(w, http.StatusBadRequest, "INVALID_PARAM", "codes must list at least one airport", "codes")
+		return
+	}
+	if len(req.Codes) > matrixMaxAirports {
+		s.respondFieldError(w, http.StatusBadRequest, "INVALID_PARAM",
+			fmt.Sprintf("Too many airports (max %d)", matrixMaxAirports), "codes")
+		return
+	}
+
+	type unresolvedCode struct {
+		Index int    `json:"index"`
+		Code  string `json:"code"`
+	}
+	resolved := make([]*airports.Airport, len(req.Codes))
+	icaos := make([]*string, len(req.Codes))
+	unresolved := []unresolvedCode{}
+	for i, code := range req.Codes {
+		apt, err := s.airports.GetByCode(strings.TrimSpace(code))
+		if err != nil {
+			unresolved = append(unresolved, unresolvedCode{i, code})
+			continue
+		}
+		resolved[i] = apt
+		icaos[i] = &apt.ICAO
+	}
+
+	units := requestUnits(r.URL.Query().Get("units"))
+	distances, bearings, unit := airports.DistanceMatrix(resolved, units)
+
+	s.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"airports":      icaos,
+		"distances":     distances,
+		"bearings":      bearings,
+		"distance_unit": unit,
+		"units":         units,
+		"count":         len(req.Codes) - len(unresolved),
+		"unresolved":    unresolved,
+	})
+}
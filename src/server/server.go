@@ -2,8 +2,11 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io/fs"
 	"log"
+	"net"
 	"net/http"
 	"time"
 
@@ -11,14 +14,25 @@ import (
 	"github.com/apimgr/airports/src/geoip"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/graphql-go/graphql"
 )
 
 // Server holds application dependencies
 type Server struct {
 	airports *airports.Service
-	geoip    *geoip.Service
+	geoip    GeoIPLookup
 	devMode  bool
 	router   *chi.Mux
+
+	graphqlSchema *graphql.Schema
+	tiles         *tileCache
+	limiter       *rateLimiter
+}
+
+// GeoIPLookup resolves IP addresses to locations. *geoip.Service implements it.
+type GeoIPLookup interface {
+	Lookup(ip net.IP) (*geoip.GeoLocation, error)
+	LookupString(ip string) (*geoip.GeoLocation, error)
 }
 
 // Response is the standard API response format
@@ -37,7 +51,7 @@ type ErrorData struct {
 }
 
 // New creates a new server instance
-func New(airportSvc *airports.Service, geoipSvc *geoip.Service, devMode bool) *Server {
+func New(airportSvc *airports.Service, geoipSvc GeoIPLookup, devMode bool) *Server {
 	// Initialize templates
 	if err := initTemplates(); err != nil {
 		log.Printf("Warning: Failed to load templates: %v", err)
@@ -47,7 +61,15 @@ func New(airportSvc *airports.Service, geoipSvc *geoip.Service, devMode bool) *S
 		airports: airportSvc,
 		geoip:    geoipSvc,
 		devMode:  devMode,
+		tiles:    newTileCache(),
+		limiter:  newRateLimiter(),
+	}
+
+	schema, err := s.buildGraphQLSchema()
+	if err != nil {
+		log.Printf("Warning: Failed to build GraphQL schema: %v", err)
 	}
+	s.graphqlSchema = schema
 
 	s.setupRouter()
 	return s
@@ -64,17 +86,19 @@ func (s *Server) setupRouter() {
 
 	// Middleware
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
+	r.Use(realIP)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
+	r.Use(compress)
 	r.Use(middleware.Timeout(60 * time.Second))
 
 	// CORS
 	r.Use(func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, If-None-Match, If-Modified-Since, X-API-Key")
+			w.Header().Set("Access-Control-Expose-Headers", "ETag, Last-Modified, Link, Retry-After, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset")
 
 			if r.Method == "OPTIONS" {
 				w.WriteHeader(http.StatusOK)
@@ -115,23 +139,50 @@ func (s *Server) setupRouter() {
 
 	// API v1 routes
 	r.Route("/api/v1", func(r chi.Router) {
+		r.Use(s.rateLimit)
+
 		// API Documentation endpoints
 		r.Get("/openapi", s.handleSwaggerUI)
 		r.Get("/openapi.json", s.handleOpenAPISpec)
 		r.Get("/graphql", s.handleGraphQLPlayground)
 		r.Post("/graphql", s.handleGraphQL)
 
-		// Airport endpoints
-		r.Get("/airports", s.handleGetAirports)
-		r.Get("/airports.json", s.handleGetAirportsJSON)
-		r.Get("/airports/{code}", s.handleGetAirportByCode)
-		r.Get("/airports/search", s.handleSearchAirports)
-		r.Get("/airports/nearby", s.handleNearbyAirports)
-		r.Get("/airports/bbox", s.handleBBoxAirports)
-		r.Get("/airports/autocomplete", s.handleAutocomplete)
-		r.Get("/airports/countries", s.handleGetCountries)
-		r.Get("/airports/states/{country}", s.handleGetStates)
+		// Airport endpoints. Responses that depend only on the dataset and
+		// the request are cacheable; stats also report reload status.
+		r.Group(func(r chi.Router) {
+			r.Use(s.cached(settingCacheExport, fallbackCacheExport))
+			r.Get("/airports/export", s.handleExportNegotiated)
+			for _, format := range exportFormats {
+				r.Get("/airports."+format.ext, s.handleExport(format.ext))
+				r.Get("/airports/search."+format.ext, s.handleExport(format.ext))
+			}
+
+			// Vector tiles
+			r.Get("/tiles/{z}/{x}/{y}.mvt", s.handleTile)
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(s.cached(settingCacheAPI, fallbackCacheAPI))
+			r.Get("/airports", s.handleGetAirports)
+			r.Get("/airports/{code}", s.handleGetAirportByCode)
+			r.Get("/airports/search", s.handleSearchAirports)
+			r.Get("/airports/nearby", s.handleNearbyAirports)
+			r.Get("/airports/nearest", s.handleNearestAirports)
+			r.Get("/airports/bbox", s.handleBBoxAirports)
+			r.Get("/airports/autocomplete", s.handleAutocomplete)
+			r.Get("/airports/countries", s.handleGetCountries)
+			r.Get("/airports/countries/{country}", s.handleGetCountryAirports)
+			r.Get("/airports/states/{country}", s.handleGetStates)
+			r.Get("/airports/states/{country}/{state}", s.handleGetStateAirports)
+			r.Get("/airports/version", s.handleDatasetVersion)
+			r.Get("/airports/changes", s.handleDatasetChanges)
+
+			// Routes
+			r.Get("/route", s.handleRoute)
+		})
+		r.Post("/airports/within", s.handleWithinPolygon)
+		r.Post("/airports/along-route", s.handleAlongRoute)
 		r.Get("/airports/stats", s.handleAirportStats)
+		r.Post("/route/matrix", s.handleDistanceMatrix)
 
 		// GeoIP endpoints
 		r.Get("/geoip", s.handleGeoIPLookup)
@@ -151,6 +202,14 @@ func (s *Server) setupRouter() {
 			r.Post("/admin/database/test", s.handleAdminDatabaseTestAPI)
 			r.Get("/admin/logs", s.handleAdminLogsAPI)
 			r.Get("/admin/health", s.handleAdminHealthAPI)
+			r.Post("/admin/airports/reload", s.handleAdminAirportsReloadAPI)
+			r.Get("/admin/airports/overrides", s.handleAdminAirportOverridesAPI)
+			r.Post("/admin/airports/{icao}", s.handleAdminAirportCreateAPI)
+			r.Put("/admin/airports/{icao}", s.handleAdminAirportReplaceAPI)
+			r.Patch("/admin/airports/{icao}", s.handleAdminAirportPatchAPI)
+			r.Delete("/admin/airports/{icao}", s.handleAdminAirportDeleteAPI)
+			r.Delete("/admin/airports/{icao}/override", s.handleAdminAirportRevertAPI)
+			r.Get("/admin/airports/{icao}/history", s.handleAdminAirportHistoryAPI)
 		})
 	})
 
@@ -176,13 +235,36 @@ func (s *Server) respondJSON(w http.ResponseWriter, status int, data interface{}
 	json.NewEncoder(w).Encode(resp)
 }
 
+// decodeJSONBody decodes a JSON request body of at most maxBytes into v,
+// writing an error and returning false if it is too large or malformed
+func (s *Server) decodeJSONBody(w http.ResponseWriter, r *http.Request, maxBytes int64, v interface{}) bool {
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBytes)).Decode(v)
+
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		s.respondError(w, http.StatusRequestEntityTooLarge, "BODY_TOO_LARGE",
+			fmt.Sprintf("Request body exceeds %d MB", maxBytes>>20))
+		return false
+	case err != nil:
+		s.respondError(w, http.StatusBadRequest, "INVALID_JSON", "Invalid JSON")
+		return false
+	}
+	return true
+}
+
 func (s *Server) respondError(w http.ResponseWriter, status int, code, message string) {
+	s.respondFieldError(w, status, code, message, "")
+}
+
+// respondFieldError responds with an error caused by a specific request parameter
+func (s *Server) respondFieldError(w http.ResponseWriter, status int, code, message, field string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 
 	resp := Response{
 		Success:   false,
-		Error:     &ErrorData{Code: code, Message: message},
+		Error:     &ErrorData{Code: code, Message: message, Field: field},
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
 	}
 
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/apimgr/airports/src/airports"
+	"github.com/apimgr/airports/src/database"
+)
+
+// Settings that shape query handling, with the defaults used when the
+// database has no value (or there is no database). Values come from the
+// database's settings cache, so admin changes apply to the next request.
+const (
+	settingDefaultUnits     = "server.default_units"
+	settingNearbyMaxRadius  = "features.nearby_max_radius"
+	settingSearchMaxResults = "features.search_max_results"
+	settingCacheAPI         = "cache.api_control"
+	settingCacheExport      = "cache.export_control"
+	settingCompression      = "cache.compression"
+	settingRateLimitEnabled = "api.rate_limit_enabled"
+	settingRateLimit        = "api.rate_limit_requests"
+	settingRateLimitKeys    = "api.rate_limit_keys"
+	settingTrustedProxies   = "server.trusted_proxies"
+
+	fallbackUnits           = airports.UnitImperial
+	fallbackNearbyMaxRadius = 500.0 // km
+	fallbackSearchMax       = 1000
+	fallbackCacheAPI        = "public, max-age=300"
+	fallbackCacheExport     = "public, max-age=3600"
+	fallbackRateLimit       = 100 // Requests per minute
+)
+
+// defaultUnits returns the configured default unit system
+func defaultUnits() string {
+	value := database.GetSettingValue(settingDefaultUnits, fallbackUnits)
+	if !airports.ValidUnits(value) {
+		return fallbackUnits
+	}
+	return airports.ParseUnits(value)
+}
+
+// requestUnits resolves a units parameter, falling back to the configured
+// default when it is empty or unknown
+func requestUnits(param string) string {
+	if airports.ValidUnits(param) {
+		return airports.ParseUnits(param)
+	}
+	return defaultUnits()
+}
+
+// nearbyMaxRadius returns the largest radius, in km, a nearby or corridor
+// search may use
+func nearbyMaxRadius() float64 {
+	radius := database.GetSettingFloat(settingNearbyMaxRadius, fallbackNearbyMaxRadius)
+	if radius <= 0 {
+		return fallbackNearbyMaxRadius
+	}
+	return radius
+}
+
+// searchMaxResults returns the most results one search or list page may hold
+func searchMaxResults() int {
+	limit := database.GetSettingInt(settingSearchMaxResults, fallbackSearchMax)
+	if limit <= 0 {
+		return fallbackSearchMax
+	}
+	return limit
+}
+
+// cacheControl returns the Cache-Control policy in a cache setting. An
+// empty policy sends no Cache-Control header.
+func cacheControl(setting, fallback string) string {
+	return strings.TrimSpace(database.GetSettingValue(setting, fallback))
+}
+
+// compressionEnabled reports whether responses may be compressed
+func compressionEnabled() bool {
+	return database.GetSettingBool(settingCompression, true)
+}
+
+// rateLimitRequests returns the requests per minute each client may make,
+// and whether rate limiting is enabled
+func rateLimitRequests() (int, bool) {
+	if !database.GetSettingBool(settingRateLimitEnabled, false) {
+		return 0, false
+	}
+	limit := database.GetSettingInt(settingRateLimit, fallbackRateLimit)
+	if limit <= 0 {
+		return fallbackRateLimit, true
+	}
+	return limit, true
+}
+
+// rateLimitKey reports whether an API key is one of the configured keys,
+// which are rate limited on their own instead of by IP
+func rateLimitKey(key string) bool {
+	for _, k := range strings.Split(database.GetSettingValue(settingRateLimitKeys, ""), ",") {
+		if k = strings.TrimSpace(k); k != "" && k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// trustedProxies returns the networks of the proxies whose forwarding
+// headers are trusted. Invalid entries are skipped.
+func trustedProxies() []*net.IPNet {
+	networks, _ := parseNetworks(database.GetSettingValue(settingTrustedProxies, ""))
+	return networks
+}
+
+// parseNetworks parses a comma-separated list of IP addresses and CIDR
+// ranges. It returns the valid networks and an error naming the first
+// invalid entry.
+func parseNetworks(value string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	var firstErr error
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("invalid address %q", entry)
+				}
+				continue
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("invalid network %q", entry)
+			}
+			continue
+		}
+		networks = append(networks, network)
+	}
+	return networks, firstErr
+}
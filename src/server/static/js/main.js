@@ -94,6 +94,9 @@ function formatDistance(km, units = 'imperial') {
     if (units === 'metric') {
         return `${km.toFixed(2)} km`;
     }
+    if (units === 'nautical') {
+        return `${(km / 1.852).toFixed(2)} nm`;
+    }
     const miles = km * 0.621371;
     return `${miles.toFixed(2)} mi`;
 }
@@ -3,7 +3,9 @@ package server
 import (
 	"embed"
 	"html/template"
+	"io/fs"
 	"net/http"
+	"path"
 )
 
 //go:embed templates/*.html
@@ -12,19 +14,41 @@ var templateFS embed.FS
 //go:embed static/**/*
 var staticFS embed.FS
 
-var templates *template.Template
+// templates holds one set per page, pairing base.html with the page's
+// "content" block. Each page defines the same block, so they cannot share
+// a set.
+var templates map[string]*template.Template
 
 // initTemplates loads and parses all HTML templates
 func initTemplates() error {
-	var err error
-	templates, err = template.ParseFS(templateFS, "templates/*.html")
-	return err
+	pages, err := fs.Glob(templateFS, "templates/*.html")
+	if err != nil {
+		return err
+	}
+
+	templates = make(map[string]*template.Template, len(pages))
+	for _, page := range pages {
+		name := path.Base(page)
+		if name == "base.html" {
+			continue
+		}
+		t, err := template.ParseFS(templateFS, "templates/base.html", page)
+		if err != nil {
+			return err
+		}
+		templates[name] = t
+	}
+	return nil
 }
 
-// renderTemplate renders an HTML template with data
+// renderTemplate renders a page within the base layout with data
 func (s *Server) renderTemplate(w http.ResponseWriter, name string, data interface{}) {
-	err := templates.ExecuteTemplate(w, name, data)
-	if err != nil {
+	t, ok := templates[name]
+	if !ok {
+		http.Error(w, "Template error", http.StatusInternalServerError)
+		return
+	}
+	if err := t.ExecuteTemplate(w, "base.html", data); err != nil {
 		http.Error(w, "Template error", http.StatusInternalServerError)
 	}
 }
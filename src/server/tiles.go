@@ -0,0 +1,202 @@
+package server
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/apimgr/airports/src/airports"
+	"github.com/go-chi/chi/v5"
+)
+
+// Vector tile settings
+const (
+	tileMaxZoom        = 22
+	tileMinZoomAll     = 5    // Below this zoom only airports with an IATA code are drawn
+	tileThinMaxZoom    = 10   // Below this zoom at most one airport is drawn per grid cell
+	tileThinCellSize   = 256  // Grid cell size in tile units (16px on a 256px tile)
+	tileBufferUnits    = 64   // Airports this far outside the tile are included for label overlap
+	tileCacheMaxTiles  = 4096 // Encoded tiles kept in memory
+	tileLayerName      = "airports"
+	tileContentTypeMVT = "application/vnd.mapbox-vector-tile"
+)
+
+// handleTile serves airports as a Mapbox Vector Tile
+func (s *Server) handleTile(w http.ResponseWriter, r *http.Request) {
+	z, err := strconv.Atoi(chi.URLParam(r, "z"))
+	if err != nil || z < 0 || z > tileMaxZoom {
+		s.respondFieldError(w, http.StatusBadRequest, "INVALID_PARAM", fmt.Sprintf("Invalid zoom (must be 0-%d)", tileMaxZoom), "z")
+		return
+	}
+
+	n := 1 << z
+	x, err := strconv.Atoi(chi.URLParam(r, "x"))
+	if err != nil || x < 0 || x >= n {
+		s.respondFieldError(w, http.StatusBadRequest, "INVALID_PARAM", fmt.Sprintf("Invalid x (must be 0-%d at zoom %d)", n-1, z), "x")
+		return
+	}
+
+	y, err := strconv.Atoi(chi.URLParam(r, "y"))
+	if err != nil || y < 0 || y >= n {
+		s.respondFieldError(w, http.StatusBadRequest, "INVALID_PARAM", fmt.Sprintf("Invalid y (must be 0-%d at zoom %d)", n-1, z), "y")
+		return
+	}
+
+	tile := s.tiles.get(s.airports, z, x, y)
+
+	w.Header().Set("Content-Type", tileContentTypeMVT)
+	w.Header().Set("Content-Length", strconv.Itoa(len(tile)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(tile)
+}
+
+// renderTile builds the airport layer for one tile
+func renderTile(svc *airports.Service, z, x, y int) []byte {
+	minLat, maxLat, minLon, maxLon := tileBounds(z, x, y)
+
+	// Widen the query by the buffer, in degrees at this zoom. At the edges of
+	// the world the buffer wraps across the antimeridian.
+	buffer := float64(tileBufferUnits) / mvtExtent * 360 / math.Exp2(float64(z))
+	west, east := minLon-buffer, maxLon+buffer
+	if east-west >= 360 {
+		west, east = -180, 180
+	} else {
+		west, east = wrapLon(west), wrapLon(east)
+	}
+	candidates := svc.GetInBoundingBox(math.Max(minLat-buffer, -90), math.Min(maxLat+buffer, 90), west, east)
+
+	// Most important airports first, so thinning keeps them
+	sort.Slice(candidates, func(i, j int) bool {
+		pi, pj := tilePriority(candidates[i]), tilePriority(candidates[j])
+		if pi != pj {
+			return pi > pj
+		}
+		return candidates[i].ICAO < candidates[j].ICAO
+	})
+
+	layer := newMVTLayer(tileLayerName)
+	occupied := make(map[[2]int]bool)
+
+	for _, apt := range candidates {
+		if z < tileMinZoomAll && apt.IATA == "" {
+			continue
+		}
+
+		// An airport across the antimeridian is drawn in the buffer on
+		// that side, and at low zooms on both sides of the world
+		for _, shift := range []float64{0, -360, 360} {
+			px, py := tilePixel(z, x, y, apt.Lat, apt.Lon+shift)
+			if px < -tileBufferUnits || px > mvtExtent+tileBufferUnits ||
+				py < -tileBufferUnits || py > mvtExtent+tileBufferUnits {
+				continue
+			}
+
+			if z < tileThinMaxZoom {
+				cell := [2]int{floorDiv(px, tileThinCellSize), floorDiv(py, tileThinCellSize)}
+				if occupied[cell] {
+					continue
+				}
+				occupied[cell] = true
+			}
+
+			layer.addPoint(0, px, py, []mvtProperty{
+				{key: "icao", str: apt.ICAO},
+				{key: "iata", str: apt.IATA},
+				{key: "name", str: apt.Name},
+				{key: "city", str: apt.City},
+				{key: "country", str: apt.Country},
+				{key: "elevation", num: int64(apt.Elevation), isNum: true},
+				{key: "type", str: apt.Type},
+			})
+		}
+	}
+
+	return encodeTile(layer)
+}
+
+// tilePriority ranks airports for thinning; higher is drawn first
+func tilePriority(apt *airports.Airport) int {
+	return apt.Importance()
+}
+
+// wrapLon brings a longitude within one turn of the world back into -180..180
+func wrapLon(lon float64) float64 {
+	switch {
+	case lon < -180:
+		return lon + 360
+	case lon > 180:
+		return lon - 360
+	}
+	return lon
+}
+
+// floorDiv divides rounding toward negative infinity
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && a < 0 {
+		q--
+	}
+	return q
+}
+
+// tileCache is an LRU cache of encoded tiles for one dataset generation
+type tileCache struct {
+	mu         sync.Mutex
+	generation uint64
+	order      *list.List // front is most recently used
+	entries    map[string]*list.Element
+}
+
+// tileCacheEntry is a cached tile
+type tileCacheEntry struct {
+	key  string
+	tile []byte
+}
+
+// newTileCache creates an empty tile cache
+func newTileCache() *tileCache {
+	return &tileCache{
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// get returns the tile from the cache, rendering it on a miss. The cache is
+// cleared when the airport dataset has been reloaded since it was filled.
+func (c *tileCache) get(svc *airports.Service, z, x, y int) []byte {
+	key := fmt.Sprintf("%d/%d/%d", z, x, y)
+	generation := svc.Generation()
+
+	c.mu.Lock()
+	if c.generation != generation {
+		c.order.Init()
+		c.entries = make(map[string]*list.Element)
+		c.generation = generation
+	}
+	if elem, ok := c.entries[key]; ok {
+		c.order.MoveToFront(elem)
+		c.mu.Unlock()
+		return elem.Value.(*tileCacheEntry).tile
+	}
+	c.mu.Unlock()
+
+	tile := renderTile(svc, z, x, y)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation == generation {
+		if _, ok := c.entries[key]; !ok {
+			c.entries[key] = c.order.PushFront(&tileCacheEntry{key: key, tile: tile})
+			if c.order.Len() > tileCacheMaxTiles {
+				oldest := c.order.Back()
+				c.order.Remove(oldest)
+				delete(c.entries, oldest.Value.(*tileCacheEntry).key)
+			}
+		}
+	}
+	return tile
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/apimgr/airports/src/airports"
+)
+
+// handleDatasetVersion returns the version of the served dataset and the
+// versions retained for handleDatasetChanges
+func (s *Server) handleDatasetVersion(w http.ResponseWriter, r *http.Request) {
+	versions := s.airports.Versions()
+	var current airports.DatasetVersion
+	if len(versions) > 0 {
+		current = versions[len(versions)-1]
+	}
+
+	s.respondJSON(w, http.StatusOK, struct {
+		airports.DatasetVersion
+		Retained []airports.DatasetVersion `json:"retained"`
+	}{current, versions})
+}
+
+// handleDatasetChanges lists the airports added, removed and modified since
+// a retained dataset version, so clients can sync without downloading the
+// whole dataset
+func (s *Server) handleDatasetChanges(w http.ResponseWriter, r *http.Request) {
+	since := strings.TrimSpace(r.URL.Query().Get("since"))
+	if since == "" {
+		s.respondFieldError(w, http.StatusBadRequest, "INVALID_PARAM", "since is required (a dataset version)", "since")
+		return
+	}
+
+	fields, ok := s.parseFieldsParam(w, r, airports.Airport{})
+	if !ok {
+		return
+	}
+
+	changes, err := s.airports.Changes(since)
+	if errors.Is(err, airports.ErrUnknownVersion) {
+		s.respondFieldError(w, http.StatusNotFound, "VERSION_NOT_FOUND",
+			fmt.Sprintf("Dataset version not retained: %s (download the full dataset instead)", since), "since")
+		return
+	}
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	s.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"since":    changes.Since.Version,
+		"version":  changes.Current.Version,
+		"added":    fields.apply(changes.Added),
+		"modified": fields.apply(changes.Modified),
+		"removed":  changes.Removed,
+		"counts": map[string]int{
+			"added":    len(changes.Added),
+			"modified": len(changes.Modified),
+			"removed":  len(changes.Removed),
+		},
+	})
+}
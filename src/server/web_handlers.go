@@ -14,16 +14,16 @@ func (s *Server) handleHome(w http.ResponseWriter, r *http.Request) {
 	data := map[string]interface{}{
 		"Title": "Home",
 	}
-	s.renderTemplate(w, "base.html", data)
+	s.renderTemplate(w, "home.html", data)
 }
 
 // handleSearch serves the search page
 func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query().Get("q")
-	var results []*airports.Airport
+	var results []airports.SearchResult
 
 	if query != "" {
-		results = s.airports.Search(query, 100, 0)
+		results, _ = s.airports.Search(query, 100, 0)
 	}
 
 	data := map[string]interface{}{
@@ -31,7 +31,7 @@ func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
 		"Query":   query,
 		"Results": results,
 	}
-	s.renderTemplate(w, "base.html", data)
+	s.renderTemplate(w, "search.html", data)
 }
 
 // handleNearby serves the nearby airports page
@@ -44,38 +44,37 @@ func (s *Server) handleNearby(w http.ResponseWriter, r *http.Request) {
 
 	data := map[string]interface{}{
 		"Title": "Find Nearby",
-		"Units": airports.ParseUnits(unitsParam),
+		"Units": requestUnits(unitsParam),
 	}
 
 	if latStr != "" && lonStr != "" {
 		lat, _ := strconv.ParseFloat(latStr, 64)
 		lon, _ := strconv.ParseFloat(lonStr, 64)
 
-		radius := 50.0
-		if radiusStr != "" {
-			radius, _ = strconv.ParseFloat(radiusStr, 64)
-		}
+		// The radius is entered in the selected unit system
+		units := requestUnits(unitsParam)
+		radiusKm, radius, _ := parseRadius(radiusStr, units, airports.DistanceToKm(nearbyDefaultRadius, units), nearbyMaxRadius())
 
 		limit := 20
 		if limitStr != "" {
 			limit, _ = strconv.Atoi(limitStr)
 		}
+		limit = min(limit, searchMaxResults())
 
-		units := airports.ParseUnits(unitsParam)
-		airportsNearby := s.airports.GetNearbyWithDistance(lat, lon, radius, limit, units)
+		airportsNearby := s.airports.GetNearbyWithDistance(lat, lon, radiusKm, limit, units)
 
-		displayRadius, radiusUnit := airports.ConvertDistance(radius, units)
+		_, radiusUnit := airports.ConvertDistance(radiusKm, units)
 
 		data["Lat"] = lat
 		data["Lon"] = lon
 		data["Radius"] = radius
-		data["DisplayRadius"] = displayRadius
+		data["DisplayRadius"] = radius
 		data["RadiusUnit"] = radiusUnit
 		data["Limit"] = limit
 		data["Airports"] = airportsNearby
 	}
 
-	s.renderTemplate(w, "base.html", data)
+	s.renderTemplate(w, "nearby.html", data)
 }
 
 // handleAirportDetail serves the airport detail page
@@ -101,7 +100,7 @@ func (s *Server) handleAirportDetail(w http.ResponseWriter, r *http.Request) {
 		data["JSON"] = string(jsonBytes)
 	}
 
-	s.renderTemplate(w, "base.html", data)
+	s.renderTemplate(w, "airport.html", data)
 }
 
 // handleStats serves the statistics page
@@ -120,5 +119,5 @@ func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
 		"Countries": countries,
 	}
 
-	s.renderTemplate(w, "base.html", data)
+	s.renderTemplate(w, "stats.html", data)
 }
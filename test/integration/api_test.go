@@ -2,11 +2,18 @@ package integration
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/apimgr/airports/src/airports"
+	"github.com/apimgr/airports/src/database"
 	"github.com/apimgr/airports/src/geoip"
 	"github.com/apimgr/airports/src/server"
 )
@@ -16,23 +23,73 @@ type Response struct {
 	Data    json.RawMessage `json:"data"`
 }
 
+// testDataPath is the small dataset shared with the airports package tests
+const testDataPath = "../../src/airports/testdata/airports.json"
+
+// testAdminToken is the admin API token setupTestDB configures
+const testAdminToken = "test-token"
+
+// stubGeoIP places every address in New York, so the GeoIP endpoints can be
+// tested without downloading the databases
+type stubGeoIP struct{}
+
+func (stubGeoIP) Lookup(ip net.IP) (*geoip.GeoLocation, error) {
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address")
+	}
+	return &geoip.GeoLocation{
+		IP:          ip.String(),
+		Country:     "US",
+		CountryName: "United States",
+		Region:      "NY",
+		RegionName:  "New York",
+		City:        "New York",
+		Latitude:    40.7128,
+		Longitude:   -74.0060,
+		TimeZone:    "America/New_York",
+	}, nil
+}
+
+func (g stubGeoIP) LookupString(ipStr string) (*geoip.GeoLocation, error) {
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address: %s", ipStr)
+	}
+	return g.Lookup(ip)
+}
+
 func setupTestServer(t *testing.T) *httptest.Server {
-	airportSvc, err := airports.NewService()
+	data, err := os.ReadFile(testDataPath)
 	if err != nil {
-		t.Fatalf("Failed to create airport service: %v", err)
+		t.Fatalf("Failed to read test data: %v", err)
 	}
 
-	// Use temp directory for GeoIP data in tests
-	tmpDir := t.TempDir()
-	geoipSvc, err := geoip.NewService(tmpDir)
+	airportSvc, err := airports.NewService(data)
 	if err != nil {
-		t.Fatalf("Failed to create geoip service: %v", err)
+		t.Fatalf("Failed to create airport service: %v", err)
 	}
 
-	srv := server.New(airportSvc, geoipSvc, false)
+	srv := server.New(airportSvc, stubGeoIP{}, false)
 	return httptest.NewServer(srv.Router())
 }
 
+// setupTestDB initializes a temporary database with admin credentials for
+// the duration of the test
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	if err := database.Initialize(database.Config{Path: t.TempDir() + "/airports.db"}); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	t.Cleanup(func() {
+		database.Close()
+		database.DB = nil
+		database.InvalidateSettingsCache()
+	})
+	if _, err := database.InitializeAdminAuth("admin", "password", testAdminToken); err != nil {
+		t.Fatalf("Failed to initialize admin auth: %v", err)
+	}
+}
+
 func TestAirportEndpoints(t *testing.T) {
 	ts := setupTestServer(t)
 	defer ts.Close()
@@ -46,8 +103,31 @@ func TestAirportEndpoints(t *testing.T) {
 		{"Get JFK by IATA", "/api/v1/airports/JFK", http.StatusOK},
 		{"Search airports", "/api/v1/airports/search?q=New+York", http.StatusOK},
 		{"List airports", "/api/v1/airports?limit=10", http.StatusOK},
+		{"List airports by cursor", "/api/v1/airports?limit=10&cursor=", http.StatusOK},
+		{"List invalid cursor", "/api/v1/airports?cursor=@@", http.StatusBadRequest},
+		{"Country airports", "/api/v1/airports/countries/US?limit=10", http.StatusOK},
+		{"Filtered airports", "/api/v1/airports?country=US&has_iata=true&min_elevation=1000&sort=elevation&order=desc", http.StatusOK},
+		{"Search sorted by name", "/api/v1/airports?q=London&sort=name", http.StatusOK},
+		{"Invalid sort", "/api/v1/airports?sort=runways", http.StatusBadRequest},
+		{"Invalid elevation", "/api/v1/airports?min_elevation=high", http.StatusBadRequest},
+		{"State airports", "/api/v1/airports/states/US/New%20York?limit=10", http.StatusOK},
 		{"Nearby airports", "/api/v1/airports/nearby?lat=40.6398&lon=-73.7789&radius=50", http.StatusOK},
+		{"Nearby invalid radius", "/api/v1/airports/nearby?lat=40.6398&lon=-73.7789&radius=abc", http.StatusBadRequest},
+		{"Nearby negative radius", "/api/v1/airports/nearby?lat=40.6398&lon=-73.7789&radius=-5", http.StatusBadRequest},
+		{"Nearby latitude out of range", "/api/v1/airports/nearby?lat=95&lon=-73.7789", http.StatusBadRequest},
+		{"Nearby NaN longitude", "/api/v1/airports/nearby?lat=40.6398&lon=NaN", http.StatusBadRequest},
+		{"Nearest airports", "/api/v1/airports/nearest?lat=-10&lon=-140&k=3", http.StatusOK},
+		{"Nearest invalid k", "/api/v1/airports/nearest?lat=0&lon=0&k=0", http.StatusBadRequest},
+		{"Nearest NaN latitude", "/api/v1/airports/nearest?lat=NaN&lon=0", http.StatusBadRequest},
+		{"Nearest NaN longitude", "/api/v1/airports/nearest?lat=0&lon=NaN", http.StatusBadRequest},
 		{"Bounding box", "/api/v1/airports/bbox?minLat=40&maxLat=41&minLon=-74&maxLon=-73", http.StatusOK},
+		{"Bounding box clusters", "/api/v1/airports/bbox?minLat=-60&maxLat=70&minLon=-170&maxLon=170&zoom=3", http.StatusOK},
+		{"Bounding box cluster=true", "/api/v1/airports/bbox?minLat=20&maxLat=50&minLon=-130&maxLon=-60&cluster=true", http.StatusOK},
+		{"Bounding box shorthand", "/api/v1/airports/bbox?bbox=-74,40,-73,41", http.StatusOK},
+		{"Bounding box antimeridian", "/api/v1/airports/bbox?bbox=170,-25,-170,-10", http.StatusOK},
+		{"Bounding box missing bound", "/api/v1/airports/bbox?maxLat=41&minLon=-74&maxLon=-73", http.StatusBadRequest},
+		{"Bounding box latitude out of range", "/api/v1/airports/bbox?minLat=40&maxLat=95&minLon=-74&maxLon=-73", http.StatusBadRequest},
+		{"Bounding box invalid zoom", "/api/v1/airports/bbox?minLat=40&maxLat=41&minLon=-74&maxLon=-73&zoom=40", http.StatusBadRequest},
 		{"Autocomplete", "/api/v1/airports/autocomplete?q=JFK", http.StatusOK},
 		{"Get countries", "/api/v1/airports/countries", http.StatusOK},
 		{"Get states", "/api/v1/airports/states/US", http.StatusOK},
@@ -90,6 +170,7 @@ func TestGeoIPEndpoints(t *testing.T) {
 	}{
 		{"Lookup 8.8.8.8", "/api/v1/geoip/8.8.8.8", http.StatusOK},
 		{"Nearby airports by IP", "/api/v1/geoip/airports/nearby?ip=8.8.8.8", http.StatusOK},
+		{"Nearby airports by IP invalid radius", "/api/v1/geoip/airports/nearby?ip=8.8.8.8&radius=-5", http.StatusBadRequest},
 		{"Invalid IP", "/api/v1/geoip/invalid", http.StatusBadRequest},
 	}
 
@@ -150,15 +231,1092 @@ func TestFullDatabaseExport(t *testing.T) {
 		t.Errorf("Expected application/json content type")
 	}
 
-	// Verify it's a large response (should be several MB)
+	// Every airport in the dataset is exported
 	var data map[string]interface{}
 	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
 		t.Fatalf("Failed to decode JSON: %v", err)
 	}
 
-	if len(data) < 1000 {
-		t.Errorf("Expected at least 1000 airports in export, got %d", len(data))
+	raw, err := os.ReadFile(testDataPath)
+	if err != nil {
+		t.Fatalf("Failed to read test data: %v", err)
+	}
+	var want map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &want); err != nil {
+		t.Fatalf("Failed to decode test data: %v", err)
+	}
+	if len(data) != len(want) {
+		t.Errorf("Expected %d airports in export, got %d", len(want), len(data))
 	}
 
 	t.Logf("Exported %d airports", len(data))
 }
+
+func TestGraphQLEndpoint(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Close()
+
+	query := `{"query":"{ airport(code: \"KJFK\") { icao name coordinates { lat lon } nearby(radius: 50, limit: 5) { icao distance } } }"}`
+	resp, err := http.Post(ts.URL+"/api/v1/graphql", "application/json", strings.NewReader(query))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Data struct {
+			Airport struct {
+				ICAO   string `json:"icao"`
+				Nearby []struct {
+					ICAO     string  `json:"icao"`
+					Distance float64 `json:"distance"`
+				} `json:"nearby"`
+			} `json:"airport"`
+		} `json:"data"`
+		Errors []map[string]interface{} `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if len(result.Errors) > 0 {
+		t.Fatalf("Unexpected GraphQL errors: %v", result.Errors)
+	}
+	if result.Data.Airport.ICAO != "KJFK" {
+		t.Errorf("Expected KJFK, got %q", result.Data.Airport.ICAO)
+	}
+	if len(result.Data.Airport.Nearby) == 0 {
+		t.Error("Expected nearby airports for KJFK")
+	}
+
+	// The caller's IP is the connection's, not a forwarding header from an
+	// untrusted client
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+"/api/v1/graphql", strings.NewReader(`{"query":"{ geoip { ip } }"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	var lookup struct {
+		Data struct {
+			GeoIP struct {
+				IP string `json:"ip"`
+			} `json:"geoip"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&lookup); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if lookup.Data.GeoIP.IP != "127.0.0.1" {
+		t.Errorf("Expected the connection's IP, got %q", lookup.Data.GeoIP.IP)
+	}
+}
+
+func TestSearchPagination(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/v1/airports/search?q=international&limit=5")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if !strings.Contains(resp.Header.Get("Link"), `rel="next"`) {
+		t.Errorf("Expected Link header with rel=\"next\", got %q", resp.Header.Get("Link"))
+	}
+
+	var apiResp Response
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	var page struct {
+		Airports []json.RawMessage `json:"airports"`
+		Total    int               `json:"total"`
+		Next     *string           `json:"next"`
+		Prev     *string           `json:"prev"`
+	}
+	if err := json.Unmarshal(apiResp.Data, &page); err != nil {
+		t.Fatalf("Failed to decode page: %v", err)
+	}
+
+	if len(page.Airports) != 5 {
+		t.Errorf("Expected 5 airports, got %d", len(page.Airports))
+	}
+	if page.Total <= len(page.Airports) {
+		t.Errorf("Expected total across all pages, got %d", page.Total)
+	}
+	if page.Next == nil || page.Prev != nil {
+		t.Errorf("Expected next link and no prev link on the first page")
+	}
+}
+
+func TestFieldSelection(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/v1/airports/nearby?lat=40.6398&lon=-73.7789&radius=50&fields=icao,name,distance")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var apiResp Response
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	var data struct {
+		Airports []map[string]interface{} `json:"airports"`
+	}
+	if err := json.Unmarshal(apiResp.Data, &data); err != nil {
+		t.Fatalf("Failed to decode data: %v", err)
+	}
+	if len(data.Airports) == 0 {
+		t.Fatal("Expected nearby airports")
+	}
+	for _, apt := range data.Airports {
+		if len(apt) != 3 || apt["icao"] == nil || apt["name"] == nil || apt["distance"] == nil {
+			t.Errorf("Expected only icao, name and distance, got %v", apt)
+		}
+	}
+
+	resp, err = http.Get(ts.URL + "/api/v1/airports/KJFK?fields=icao,runway")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for unknown field, got %d", resp.StatusCode)
+	}
+}
+
+func TestExportFormats(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Close()
+
+	tests := []struct {
+		name        string
+		endpoint    string
+		accept      string
+		contentType string
+		prefix      string
+	}{
+		{"CSV", "/api/v1/airports.csv?country=US", "", "text/csv", "icao,iata,name"},
+		{"GeoJSON", "/api/v1/airports.geojson?country=US", "", "application/geo+json", `{"type":"FeatureCollection"`},
+		{"NDJSON", "/api/v1/airports.ndjson?country=US", "", "application/x-ndjson", `{"icao":`},
+		{"KML", "/api/v1/airports.kml?country=US", "", "application/vnd.google-earth.kml+xml", "<?xml"},
+		{"GPX", "/api/v1/airports.gpx?country=US", "", "application/gpx+xml", "<?xml"},
+		{"Search CSV", "/api/v1/airports/search.csv?q=New+York", "", "text/csv", "icao,iata,name"},
+		{"Accept CSV", "/api/v1/airports/export?country=US", "text/csv", "text/csv", "icao,iata,name"},
+		{"Accept GeoJSON", "/api/v1/airports/export?country=US", "application/geo+json", "application/geo+json", `{"type":"FeatureCollection"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, ts.URL+tt.endpoint, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+			}
+			if got := resp.Header.Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Expected content type %s, got %s", tt.contentType, got)
+			}
+
+			body := make([]byte, len(tt.prefix))
+			if _, err := io.ReadFull(resp.Body, body); err != nil || string(body) != tt.prefix {
+				t.Errorf("Expected body to start with %q, got %q", tt.prefix, body)
+			}
+		})
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/v1/airports/export", nil)
+	req.Header.Set("Accept", "image/png")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotAcceptable {
+		t.Errorf("Expected status 406 for unsupported Accept, got %d", resp.StatusCode)
+	}
+
+	// Placemark IDs must be XML IDs, even for codes starting with a digit
+	resp, err = http.Get(ts.URL + "/api/v1/airports.kml?country=US")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	kml, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(kml), `<Placemark id="apt-00AK">`) {
+		t.Errorf("Expected a prefixed placemark id for 00AK")
+	}
+}
+
+func TestGeoJSONOutput(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Close()
+
+	endpoints := []string{
+		"/api/v1/airports/nearby?lat=40.6398&lon=-73.7789&radius=50&format=geojson",
+		"/api/v1/airports/bbox?minLat=40&maxLat=41&minLon=-74&maxLon=-73&format=geojson",
+		"/api/v1/airports/search?q=New+York&format=geojson",
+	}
+
+	for _, endpoint := range endpoints {
+		resp, err := http.Get(ts.URL + endpoint)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+
+		if got := resp.Header.Get("Content-Type"); got != "application/geo+json" {
+			t.Errorf("%s: expected application/geo+json, got %s", endpoint, got)
+		}
+		if !hasVary(resp.Header, "Accept") {
+			t.Errorf("%s: expected Vary: Accept, got %v", endpoint, resp.Header.Values("Vary"))
+		}
+
+		var collection struct {
+			Type     string `json:"type"`
+			Features []struct {
+				Geometry struct {
+					Type        string    `json:"type"`
+					Coordinates []float64 `json:"coordinates"`
+				} `json:"geometry"`
+				Properties map[string]interface{} `json:"properties"`
+			} `json:"features"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&collection); err != nil {
+			t.Fatalf("%s: failed to decode GeoJSON: %v", endpoint, err)
+		}
+		resp.Body.Close()
+
+		if collection.Type != "FeatureCollection" || len(collection.Features) == 0 {
+			t.Fatalf("%s: expected a non-empty FeatureCollection", endpoint)
+		}
+		feature := collection.Features[0]
+		if feature.Geometry.Type != "Point" || len(feature.Geometry.Coordinates) != 2 {
+			t.Errorf("%s: expected Point geometry, got %+v", endpoint, feature.Geometry)
+		}
+		if strings.Contains(endpoint, "nearby") && feature.Properties["distance"] == nil {
+			t.Errorf("%s: expected distance property", endpoint)
+		}
+
+		// The JSON representation of the same URL depends on Accept too
+		jsonEndpoint := strings.TrimSuffix(endpoint, "&format=geojson")
+		resp, err = http.Get(ts.URL + jsonEndpoint)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		if !hasVary(resp.Header, "Accept") {
+			t.Errorf("%s: expected Vary: Accept, got %v", jsonEndpoint, resp.Header.Values("Vary"))
+		}
+	}
+}
+
+// hasVary reports whether a response's Vary header names a request header
+func hasVary(h http.Header, name string) bool {
+	for _, value := range h.Values("Vary") {
+		for _, field := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(field), name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func TestBoundingBoxClusters(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Close()
+
+	get := func(path string) map[string]interface{} {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d", path, resp.StatusCode)
+		}
+		var result struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatalf("%s: failed to decode response: %v", path, err)
+		}
+		return result.Data
+	}
+
+	tests := []struct {
+		name string
+		path string
+		cell float64
+	}{
+		{"Sixteen cells across the box", "/api/v1/airports/bbox?minLat=20&maxLat=50&minLon=-130&maxLon=-50&cluster=true", 5},
+		{"Antimeridian", "/api/v1/airports/bbox?minLat=-25&maxLat=-10&minLon=170&maxLon=-170&cluster=true", 1.25},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if data := get(tt.path); data["cell_degrees"] != tt.cell {
+				t.Errorf("Expected %g degree cells, got %v", tt.cell, data["cell_degrees"])
+			}
+		})
+	}
+
+	// A box with equal longitudes has no width, not a full turn of the globe
+	data := get("/api/v1/airports/bbox?minLat=40&maxLat=41&minLon=-73.77890015&maxLon=-73.77890015&cluster=true")
+	if _, ok := data["cell_degrees"]; ok {
+		t.Errorf("Expected a zero-width box to be unclustered, got %v degree cells", data["cell_degrees"])
+	}
+	if data["count"] != float64(1) {
+		t.Errorf("Expected only KJFK in a zero-width box, got %v", data["count"])
+	}
+}
+
+func TestVectorTiles(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/v1/tiles/0/0/0.mvt")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "application/vnd.mapbox-vector-tile" {
+		t.Errorf("Expected vector tile content type, got %s", got)
+	}
+
+	tile, _ := io.ReadAll(resp.Body)
+	// A tile with a layer starts with field 3 (layers), wire type 2
+	if len(tile) == 0 || tile[0] != 0x1a {
+		t.Errorf("Expected a vector tile with an airports layer")
+	}
+	if !strings.Contains(string(tile), "airports") || !strings.Contains(string(tile), "icao") {
+		t.Errorf("Expected the world tile to include the airports layer with icao properties")
+	}
+
+	// Fiji is just across the antimeridian from the western edge of the world
+	resp, err = http.Get(ts.URL + "/api/v1/tiles/1/0/1.mvt")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	tile, _ = io.ReadAll(resp.Body)
+	if !strings.Contains(string(tile), "NFFN") && !strings.Contains(string(tile), "NFNA") {
+		t.Errorf("Expected the tile buffer to wrap across the antimeridian")
+	}
+
+	for _, endpoint := range []string{"/api/v1/tiles/23/0/0.mvt", "/api/v1/tiles/2/4/0.mvt", "/api/v1/tiles/2/0/-1.mvt"} {
+		resp, err := http.Get(ts.URL + endpoint)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", endpoint, resp.StatusCode)
+		}
+	}
+}
+
+func TestGeometryQueries(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		endpoint string
+		body     string
+		status   int
+		want     string
+	}{
+		{
+			"Polygon",
+			"/api/v1/airports/within",
+			`{"type":"Polygon","coordinates":[[[-74.3,40.5],[-73.7,40.5],[-73.7,40.9],[-74.3,40.9],[-74.3,40.5]]]}`,
+			http.StatusOK, "KJFK",
+		},
+		{
+			"Antimeridian MultiPolygon feature",
+			"/api/v1/airports/within",
+			`{"type":"Feature","geometry":{"type":"MultiPolygon","coordinates":[[[[176,-19],[-178,-19],[-178,-16],[176,-16],[176,-19]]]]}}`,
+			http.StatusOK, "NFFN",
+		},
+		{
+			"Route corridor",
+			"/api/v1/airports/along-route?corridor=30&units=metric",
+			`{"type":"LineString","coordinates":[[-73.78,40.64],[-0.46,51.47]]}`,
+			http.StatusOK, "EGLL",
+		},
+		{"Unsupported geometry", "/api/v1/airports/within", `{"type":"Point","coordinates":[0,0]}`, http.StatusBadRequest, "INVALID_GEOMETRY"},
+		{"Short route", "/api/v1/airports/along-route", `{"type":"LineString","coordinates":[[0,0]]}`, http.StatusBadRequest, "INVALID_GEOMETRY"},
+		{"NaN corridor", "/api/v1/airports/along-route?corridor=NaN", `{"type":"LineString","coordinates":[[0,0],[1,1]]}`, http.StatusBadRequest, `"field":"corridor"`},
+		{"Negative corridor", "/api/v1/airports/along-route?corridor=-5", `{"type":"LineString","coordinates":[[0,0],[1,1]]}`, http.StatusBadRequest, `"field":"corridor"`},
+		{"Malformed body", "/api/v1/airports/within", `{"type":`, http.StatusBadRequest, "INVALID_JSON"},
+		{
+			"Too many route positions",
+			"/api/v1/airports/along-route",
+			`{"type":"LineString","coordinates":[` + strings.Repeat("[0,0],", 2000) + `[0,0]]}`,
+			http.StatusBadRequest, "too many route positions",
+		},
+		{
+			"Too many polygon vertices",
+			"/api/v1/airports/within",
+			`{"type":"Polygon","coordinates":[[` + strings.Repeat("[0,0],", 100000) + `[0,0]]]}`,
+			http.StatusBadRequest, "too many polygon vertices",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(ts.URL+tt.endpoint, "application/geo+json", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, resp.StatusCode)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if !strings.Contains(string(body), tt.want) {
+				t.Errorf("Expected response to contain %q", tt.want)
+			}
+		})
+	}
+}
+
+func TestRouteEndpoint(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/v1/route?from=JFK&via=KORD&to=EGLL&waypoints=4")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Data struct {
+			Legs []struct {
+				From      string `json:"from"`
+				To        string `json:"to"`
+				Distance  struct{ Km, Nm float64 }
+				Waypoints struct {
+					Type        string      `json:"type"`
+					Coordinates [][]float64 `json:"coordinates"`
+				} `json:"waypoints"`
+			} `json:"legs"`
+			Distance struct{ Km float64 } `json:"distance"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	legs := result.Data.Legs
+	if len(legs) != 2 || legs[0].From != "KJFK" || legs[0].To != "KORD" || legs[1].To != "EGLL" {
+		t.Fatalf("Expected legs KJFK-KORD-EGLL, got %+v", legs)
+	}
+	if total := legs[0].Distance.Km + legs[1].Distance.Km; total-result.Data.Distance.Km > 0.001 || result.Data.Distance.Km-total > 0.001 {
+		t.Errorf("Expected total %.1f km to equal the sum of the legs %.1f km", result.Data.Distance.Km, total)
+	}
+	if legs[0].Waypoints.Type != "LineString" || len(legs[0].Waypoints.Coordinates) != 6 {
+		t.Errorf("Expected a LineString with 6 positions, got %+v", legs[0].Waypoints)
+	}
+
+	for endpoint, status := range map[string]int{
+		"/api/v1/route?from=KJFK":                     http.StatusBadRequest,
+		"/api/v1/route?from=KJFK&to=ZZZZ":             http.StatusNotFound,
+		"/api/v1/route?from=KJFK&to=EGLL&waypoints=x": http.StatusBadRequest,
+	} {
+		resp, err := http.Get(ts.URL + endpoint)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Errorf("%s: expected status %d, got %d", endpoint, status, resp.StatusCode)
+		}
+	}
+}
+
+func TestDistanceMatrix(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Close()
+
+	body := `{"codes":["JFK","XXXX","EGLL"]}`
+	resp, err := http.Post(ts.URL+"/api/v1/route/matrix?units=metric", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Data struct {
+			Airports   []*string    `json:"airports"`
+			Distances  [][]*float64 `json:"distances"`
+			Unit       string       `json:"distance_unit"`
+			Unresolved []struct {
+				Index int    `json:"index"`
+				Code  string `json:"code"`
+			} `json:"unresolved"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	data := result.Data
+	if len(data.Unresolved) != 1 || data.Unresolved[0].Index != 1 || data.Unresolved[0].Code != "XXXX" {
+		t.Errorf("Expected XXXX unresolved at index 1, got %+v", data.Unresolved)
+	}
+	if len(data.Distances) != 3 || data.Distances[1] != nil || data.Distances[0][1] != nil {
+		t.Fatalf("Expected a 3x3 matrix with a null row and column for XXXX, got %v", data.Distances)
+	}
+	if data.Unit != "km" || *data.Distances[0][2] < 5500 || *data.Distances[0][2] != *data.Distances[2][0] {
+		t.Errorf("Expected a symmetric JFK-LHR distance of about 5540 km, got %v %s", *data.Distances[0][2], data.Unit)
+	}
+}
+
+func TestNauticalUnits(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/v1/airports/nearby?lat=40.6398&lon=-73.7789&radius=10&units=nautical")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data struct {
+			Airports []struct {
+				ICAO         string  `json:"icao"`
+				Distance     float64 `json:"distance"`
+				DistanceUnit string  `json:"distance_unit"`
+			} `json:"airports"`
+			Radius     float64 `json:"radius"`
+			RadiusUnit string  `json:"radius_unit"`
+			Units      string  `json:"units"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	data := result.Data
+	if data.Units != "nautical" || data.RadiusUnit != "nm" || data.Radius != 10 {
+		t.Errorf("Expected a 10 nm radius, got %v %s (%s)", data.Radius, data.RadiusUnit, data.Units)
+	}
+	if len(data.Airports) == 0 {
+		t.Fatal("Expected airports within 10 nm of KJFK")
+	}
+	for _, apt := range data.Airports {
+		if apt.DistanceUnit != "nm" || apt.Distance > 10 {
+			t.Errorf("%s: expected a distance within 10 nm, got %v %s", apt.ICAO, apt.Distance, apt.DistanceUnit)
+		}
+	}
+}
+
+// TestDefaultRadius checks that an omitted radius is 50 in the requested unit
+// on every surface. KISP is 59.8 km from KJFK: outside 50 km, inside 50 nm.
+func TestDefaultRadius(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Close()
+
+	read := func(resp *http.Response, err error) string {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	surfaces := []struct {
+		name  string
+		fetch func(units string) string
+	}{
+		{"REST", func(units string) string {
+			return read(http.Get(ts.URL + "/api/v1/airports/nearby?lat=40.6398&lon=-73.7789&units=" + units))
+		}},
+		{"GraphQL", func(units string) string {
+			query := `{"query":"{ airport(code: \"KJFK\") { nearby(units: \"` + units + `\") { icao } } }"}`
+			return read(http.Post(ts.URL+"/api/v1/graphql", "application/json", strings.NewReader(query)))
+		}},
+		{"Web", func(units string) string {
+			return read(http.Get(ts.URL + "/nearby?lat=40.6398&lon=-73.7789&units=" + units))
+		}},
+	}
+
+	for _, surface := range surfaces {
+		t.Run(surface.name, func(t *testing.T) {
+			if body := surface.fetch("metric"); strings.Contains(body, "KISP") {
+				t.Error("Expected KISP outside the default 50 km")
+			}
+			if body := surface.fetch("nautical"); !strings.Contains(body, "KISP") {
+				t.Error("Expected KISP within the default 50 nm")
+			}
+		})
+	}
+
+	body := surfaces[0].fetch("nautical")
+	if !strings.Contains(body, `"radius":50,`) || !strings.Contains(body, `"radius_unit":"nm"`) {
+		t.Errorf("Expected a 50 nm radius in the response, got %s", body)
+	}
+}
+
+func TestSettingsLimits(t *testing.T) {
+	setupTestDB(t)
+
+	ts := setupTestServer(t)
+	defer ts.Close()
+
+	type nearby struct {
+		Data struct {
+			Radius     float64 `json:"radius"`
+			RadiusUnit string  `json:"radius_unit"`
+			Units      string  `json:"units"`
+		} `json:"data"`
+	}
+	get := func() nearby {
+		resp, err := http.Get(ts.URL + "/api/v1/airports/nearby?lat=40.6398&lon=-73.7789&radius=1000")
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		var result nearby
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return result
+	}
+
+	set := func(key, value string) {
+		setting, err := database.GetSetting(key)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", key, err)
+		}
+		if err := database.SetSetting(key, value, setting.Type, setting.Category, setting.Description); err != nil {
+			t.Fatalf("Failed to set %s: %v", key, err)
+		}
+	}
+
+	if result := get(); result.Data.Units != "imperial" {
+		t.Errorf("Expected imperial by default, got %s", result.Data.Units)
+	}
+
+	// Changes apply to the next request
+	set("server.default_units", "metric")
+	set("features.nearby_max_radius", "100")
+	result := get()
+	if result.Data.Units != "metric" || result.Data.RadiusUnit != "km" || result.Data.Radius != 100 {
+		t.Errorf("Expected a radius capped at 100 km, got %v %s (%s)", result.Data.Radius, result.Data.RadiusUnit, result.Data.Units)
+	}
+
+	set("server.default_units", "nautical")
+	if result := get(); result.Data.Units != "nautical" {
+		t.Errorf("Expected nautical after the update, got %s", result.Data.Units)
+	}
+}
+
+func TestAirportTypeFilters(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Close()
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+	}{
+		{"Exclude types", "/api/v1/airports?country=US&exclude_type=heliport,closed", http.StatusOK},
+		{"Short type names", "/api/v1/airports?type=large,medium", http.StatusOK},
+		{"Runway filters", "/api/v1/airports?min_runway_length=8000&runway_surface=ASP", http.StatusOK},
+		{"Unknown type", "/api/v1/airports?type=spaceport", http.StatusBadRequest},
+		{"Invalid scheduled service", "/api/v1/airports?scheduled_service=maybe", http.StatusBadRequest},
+		{"Invalid runway length", "/api/v1/airports?min_runway_length=long", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Get(ts.URL + tt.path)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, resp.StatusCode)
+			}
+		})
+	}
+
+	// Airports without the optional details keep their original fields
+	resp, err := http.Get(ts.URL + "/api/v1/airports/KJFK")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	for _, field := range []string{"runways", "frequencies", "scheduled_service"} {
+		if _, ok := result.Data[field]; ok {
+			t.Errorf("Expected no %s for an airport without it", field)
+		}
+	}
+}
+
+func TestAirportOverrides(t *testing.T) {
+	setupTestDB(t)
+
+	ts := setupTestServer(t)
+	defer ts.Close()
+
+	send := func(method, path, body string) int {
+		req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("Failed to build request: %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testAdminToken)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", "203.0.113.5")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{"Patch", http.MethodPatch, "/api/v1/admin/airports/KJFK", `{"name": "Kennedy"}`, http.StatusOK},
+		{"Invalid latitude", http.MethodPatch, "/api/v1/admin/airports/KJFK", `{"lat": 123}`, http.StatusBadRequest},
+		{"Unknown field", http.MethodPatch, "/api/v1/admin/airports/KJFK", `{"runway": 1}`, http.StatusBadRequest},
+		{"Create existing", http.MethodPost, "/api/v1/admin/airports/KJFK", `{"name": "Kennedy", "lat": 40.6, "lon": -73.8}`, http.StatusConflict},
+		{"Create", http.MethodPost, "/api/v1/admin/airports/XTST", `{"name": "Test Field", "country": "US", "lat": 40.7, "lon": -73.9}`, http.StatusCreated},
+		{"Delete", http.MethodDelete, "/api/v1/admin/airports/KLGA", "", http.StatusOK},
+		{"Delete missing", http.MethodDelete, "/api/v1/admin/airports/KLGA", "", http.StatusNotFound},
+		{"Revert", http.MethodDelete, "/api/v1/admin/airports/KLGA/override", "", http.StatusOK},
+		{"Revert without override", http.MethodDelete, "/api/v1/admin/airports/KLGA/override", "", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := send(tt.method, tt.path, tt.body); status != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, status)
+			}
+		})
+	}
+
+	// Overrides are served and survive a reload
+	if status := send(http.MethodPost, "/api/v1/admin/airports/reload", ""); status != http.StatusOK {
+		t.Fatalf("Expected reload to succeed, got %d", status)
+	}
+	for path, name := range map[string]string{"/api/v1/airports/KJFK": "Kennedy", "/api/v1/airports/XTST": "Test Field"} {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		var result struct {
+			Data airports.Airport `json:"data"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if result.Data.Name != name {
+			t.Errorf("%s: expected name %q, got %q", path, name, result.Data.Name)
+		}
+	}
+
+	history, err := database.GetAirportOverrideHistory("KLGA", 10)
+	if err != nil {
+		t.Fatalf("Failed to read history: %v", err)
+	}
+	if len(history) != 2 || history[0].Operation != "revert" || history[1].Operation != "delete" {
+		t.Errorf("Expected delete then revert in the history, got %d entries", len(history))
+	}
+
+	// Changes are attributed to the authenticated admin
+	for _, c := range history {
+		if c.Actor != "token:"+database.TokenID(testAdminToken) || c.RemoteAddr != "127.0.0.1" {
+			t.Errorf("Expected the admin token and client address, got actor %q from %q", c.Actor, c.RemoteAddr)
+		}
+	}
+}
+
+func TestDatasetVersion(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/v1/airports/version")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var version struct {
+		Data struct {
+			Version  string            `json:"version"`
+			Hash     string            `json:"hash"`
+			Retained []json.RawMessage `json:"retained"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&version); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if version.Data.Version == "" || !strings.HasPrefix(version.Data.Hash, "sha256:") || len(version.Data.Retained) != 1 {
+		t.Fatalf("Unexpected version: %+v", version.Data)
+	}
+	if etag := resp.Header.Get("ETag"); !strings.HasPrefix(etag, `"`+version.Data.Version+"-") {
+		t.Errorf("Expected an ETag naming the version, got %s", etag)
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+	}{
+		{"Since current", "/api/v1/airports/changes?since=" + version.Data.Version, http.StatusOK},
+		{"Unknown version", "/api/v1/airports/changes?since=0123456789abcdef", http.StatusNotFound},
+		{"Missing since", "/api/v1/airports/changes", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Get(ts.URL + tt.path)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, resp.StatusCode)
+			}
+		})
+	}
+
+	resp, err = http.Get(ts.URL + "/api/v1/airports.csv")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if etag := resp.Header.Get("ETag"); !strings.HasPrefix(etag, `"`+version.Data.Version+"-") {
+		t.Errorf("Expected a versioned export ETag, got %s", etag)
+	}
+}
+
+func TestConditionalRequests(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Close()
+
+	get := func(path string, header ...string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, ts.URL+path, nil)
+		if err != nil {
+			t.Fatalf("Failed to build request: %v", err)
+		}
+		// Ask for identity unless a test picks an encoding, so the
+		// transport does not add gzip itself
+		req.Header.Set("Accept-Encoding", "identity")
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := get("/api/v1/airports/KJFK")
+	etag := resp.Header.Get("ETag")
+	if etag == "" || resp.Header.Get("Last-Modified") == "" || resp.Header.Get("Cache-Control") != "public, max-age=300" {
+		t.Fatalf("Expected cache headers, got %v", resp.Header)
+	}
+	if !hasVary(resp.Header, "Accept") || !hasVary(resp.Header, "Accept-Encoding") {
+		t.Errorf("Expected Vary: Accept and Accept-Encoding, got %v", resp.Header.Values("Vary"))
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		header []string
+		status int
+	}{
+		{"Matching ETag", "/api/v1/airports/KJFK", []string{"If-None-Match", etag}, http.StatusNotModified},
+		{"Weak ETag", "/api/v1/airports/KJFK", []string{"If-None-Match", "W/" + etag}, http.StatusNotModified},
+		{"Other parameters", "/api/v1/airports/KJFK?fields=icao", []string{"If-None-Match", etag}, http.StatusOK},
+		{"Stale ETag", "/api/v1/airports/KJFK", []string{"If-None-Match", `"stale"`}, http.StatusOK},
+		{"Wildcard", "/api/v1/airports/KJFK", []string{"If-None-Match", "*"}, http.StatusOK},
+		{"Wildcard for a missing airport", "/api/v1/airports/NOTFOUND", []string{"If-None-Match", "*"}, http.StatusNotFound},
+		{"Not modified since", "/api/v1/airports/KJFK", []string{"If-Modified-Since", resp.Header.Get("Last-Modified")}, http.StatusNotModified},
+		{"Modified since", "/api/v1/airports/KJFK", []string{"If-Modified-Since", "Mon, 01 Jan 2001 00:00:00 GMT"}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if resp := get(tt.path, tt.header...); resp.StatusCode != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, resp.StatusCode)
+			}
+		})
+	}
+
+	// Compressed representations get their own tag, which still revalidates
+	resp = get("/api/v1/airports.json", "Accept-Encoding", "br, gzip")
+	if resp.Header.Get("Content-Encoding") != "br" || !strings.HasSuffix(resp.Header.Get("ETag"), `-br"`) {
+		t.Errorf("Expected a brotli export, got %v", resp.Header)
+	}
+	if resp.Header.Get("Cache-Control") != "public, max-age=3600" {
+		t.Errorf("Expected the export cache policy, got %s", resp.Header.Get("Cache-Control"))
+	}
+	resp = get("/api/v1/airports.json", "Accept-Encoding", "gzip", "If-None-Match", resp.Header.Get("ETag"))
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("Expected 304 for a compressed tag, got %d", resp.StatusCode)
+	}
+	if !hasVary(resp.Header, "Accept") || !hasVary(resp.Header, "Accept-Encoding") {
+		t.Errorf("Expected a 304 with Vary: Accept and Accept-Encoding, got %v", resp.Header.Values("Vary"))
+	}
+
+	// Errors are not cached
+	resp = get("/api/v1/airports/NOTFOUND")
+	if resp.Header.Get("ETag") != "" || resp.Header.Get("Cache-Control") != "no-store" {
+		t.Errorf("Expected an uncached 404, got %v", resp.Header)
+	}
+}
+
+func TestRateLimiting(t *testing.T) {
+	setupTestDB(t)
+
+	set := func(key, value string) {
+		setting, err := database.GetSetting(key)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", key, err)
+		}
+		if err := database.SetSetting(key, value, setting.Type, setting.Category, setting.Description); err != nil {
+			t.Fatalf("Failed to set %s: %v", key, err)
+		}
+	}
+	set("api.rate_limit_enabled", "true")
+	set("api.rate_limit_requests", "3")
+	set("api.rate_limit_keys", "partner-key")
+
+	ts := setupTestServer(t)
+	defer ts.Close()
+
+	get := func(header ...string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, ts.URL+"/api/v1/airports/KJFK", nil)
+		if err != nil {
+			t.Fatalf("Failed to build request: %v", err)
+		}
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	for i := 0; i < 3; i++ {
+		resp := get()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Request %d: expected status 200, got %d", i+1, resp.StatusCode)
+		}
+		if resp.Header.Get("X-RateLimit-Limit") != "3" || resp.Header.Get("X-RateLimit-Remaining") != strconv.Itoa(2-i) {
+			t.Errorf("Request %d: unexpected rate limit headers %v", i+1, resp.Header)
+		}
+	}
+
+	resp := get()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("Expected status 429, got %d", resp.StatusCode)
+	}
+	if retry, err := strconv.Atoi(resp.Header.Get("Retry-After")); err != nil || retry < 1 {
+		t.Errorf("Expected a Retry-After in seconds, got %q", resp.Header.Get("Retry-After"))
+	}
+
+	tests := []struct {
+		name   string
+		header []string
+		status int
+	}{
+		{"Admin token", []string{"Authorization", "Bearer " + testAdminToken}, http.StatusOK},
+		{"Invalid admin token", []string{"Authorization", "Bearer wrong"}, http.StatusTooManyRequests},
+		{"Configured API key", []string{"X-API-Key", "partner-key"}, http.StatusOK},
+		{"Unknown API key", []string{"X-API-Key", "other-key"}, http.StatusTooManyRequests},
+		{"Spoofed X-Forwarded-For", []string{"X-Forwarded-For", "203.0.113.7"}, http.StatusTooManyRequests},
+		{"Spoofed X-Real-IP", []string{"X-Real-IP", "203.0.113.8"}, http.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if resp := get(tt.header...); resp.StatusCode != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, resp.StatusCode)
+			}
+		})
+	}
+
+	// Behind a trusted proxy each forwarded client has its own limit, and
+	// addresses the client put in front of its own are ignored
+	set("server.trusted_proxies", "127.0.0.1, ::1")
+	for i := 0; i < 3; i++ {
+		if resp := get("X-Forwarded-For", "203.0.113.7"); resp.StatusCode != http.StatusOK {
+			t.Fatalf("Forwarded request %d: expected status 200, got %d", i+1, resp.StatusCode)
+		}
+	}
+	if resp := get("X-Forwarded-For", "198.51.100.9, 203.0.113.7"); resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Expected the forwarded client to be limited, got %d", resp.StatusCode)
+	}
+
+	// Settings apply to the next request
+	set("api.rate_limit_enabled", "false")
+	if resp := get(); resp.StatusCode != http.StatusOK || resp.Header.Get("X-RateLimit-Limit") != "" {
+		t.Errorf("Expected no limit once disabled, got %d", resp.StatusCode)
+	}
+}